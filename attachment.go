@@ -0,0 +1,86 @@
+package sentry
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// defaultMaxAttachmentBytes is the default maximum size of an attachment.
+const defaultMaxAttachmentBytes = 20 * 1024 * 1024
+
+// Attachment is a file sent to Sentry along with an event, for example a
+// configuration dump, an input file or a goroutine dump.
+//
+// Attachments can be added to a Scope, to be sent with every event captured
+// in that scope, or to an EventHint, to be sent with a single event.
+type Attachment struct {
+	// Filename is the name of the file as shown in Sentry.
+	Filename string
+	// ContentType is the MIME type of the payload. If empty, Sentry infers it
+	// from the filename.
+	ContentType string
+	// Payload holds the contents of the attachment.
+	Payload []byte
+	// Open, if not nil, is called to read the contents of the attachment
+	// lazily, only when an event is about to be sent. Payload is ignored when
+	// Open is set. It may be called once for each event the attachment is
+	// sent with.
+	Open func() (io.ReadCloser, error)
+}
+
+// load returns the attachment with its payload read into memory. It returns
+// an error if the payload cannot be read or is larger than max bytes.
+func (a *Attachment) load(max int) (*Attachment, error) {
+	if a.Open == nil {
+		if max > 0 && len(a.Payload) > max {
+			return nil, fmt.Errorf("attachment %q too large: %d bytes", a.Filename, len(a.Payload))
+		}
+		return a, nil
+	}
+
+	r, err := a.Open()
+	if err != nil {
+		return nil, fmt.Errorf("attachment %q: %v", a.Filename, err)
+	}
+	defer r.Close()
+
+	var lr io.Reader = r
+	if max > 0 {
+		lr = io.LimitReader(r, int64(max)+1)
+	}
+	payload, err := ioutil.ReadAll(lr)
+	if err != nil {
+		return nil, fmt.Errorf("attachment %q: %v", a.Filename, err)
+	}
+	if max > 0 && len(payload) > max {
+		return nil, fmt.Errorf("attachment %q too large: more than %d bytes", a.Filename, max)
+	}
+	return &Attachment{
+		Filename:    a.Filename,
+		ContentType: a.ContentType,
+		Payload:     payload,
+	}, nil
+}
+
+// loadAttachments reads the payload of the event attachments, dropping those
+// that cannot be read or exceed the MaxAttachmentBytes option.
+func (client *Client) loadAttachments(event *Event) {
+	if len(event.Attachments) == 0 {
+		return
+	}
+	max := limit(client.Options().MaxAttachmentBytes, defaultMaxAttachmentBytes)
+	loaded := make([]*Attachment, 0, len(event.Attachments))
+	for _, a := range event.Attachments {
+		if a == nil {
+			continue
+		}
+		a, err := a.load(max)
+		if err != nil {
+			Logger.Printf("Attachment dropped: %v", err)
+			continue
+		}
+		loaded = append(loaded, a)
+	}
+	event.Attachments = loaded
+}
@@ -0,0 +1,89 @@
+package sentry
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestAttachmentLoad(t *testing.T) {
+	opened := 0
+	lazy := &Attachment{
+		Filename:    "dump.txt",
+		ContentType: "text/plain",
+		Open: func() (io.ReadCloser, error) {
+			opened++
+			return ioutil.NopCloser(strings.NewReader("lazy payload")), nil
+		},
+	}
+
+	got, err := lazy.load(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, got, &Attachment{Filename: "dump.txt", ContentType: "text/plain", Payload: []byte("lazy payload")})
+	assertEqual(t, opened, 1)
+
+	if _, err := lazy.load(4); err == nil {
+		t.Error("got nil error for lazy attachment larger than the limit")
+	}
+	if _, err := (&Attachment{Payload: []byte("12345")}).load(4); err == nil {
+		t.Error("got nil error for attachment larger than the limit")
+	}
+	if _, err := (&Attachment{Payload: []byte("12345")}).load(0); err != nil {
+		t.Errorf("got error %v, want no limit", err)
+	}
+
+	failing := &Attachment{
+		Open: func() (io.ReadCloser, error) {
+			return nil, errors.New("no such file")
+		},
+	}
+	if _, err := failing.load(100); err == nil {
+		t.Error("got nil error for attachment that cannot be opened")
+	}
+}
+
+func TestClientSendsAttachments(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{
+		Transport:          transport,
+		MaxAttachmentBytes: 10,
+		BeforeSend: func(event *Event, hint *EventHint) *Event {
+			if event.Message == "drop" {
+				return nil
+			}
+			return event
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opened := 0
+	scope := NewScope()
+	scope.AddAttachment(Attachment{Filename: "config.json", Payload: []byte("{}")})
+	scope.AddAttachment(Attachment{Filename: "huge.bin", Payload: make([]byte, 11)})
+	scope.AddAttachment(Attachment{
+		Filename: "lazy.txt",
+		Open: func() (io.ReadCloser, error) {
+			opened++
+			return ioutil.NopCloser(strings.NewReader("lazy")), nil
+		},
+	})
+
+	client.CaptureMessage("drop", nil, scope)
+	assertEqual(t, opened, 0, "lazy attachment read for dropped event")
+
+	hint := &EventHint{Attachments: []*Attachment{{Filename: "input.csv", Payload: []byte("a,b")}}}
+	client.CaptureMessage("keep", hint, scope)
+	assertEqual(t, opened, 1)
+
+	var filenames []string
+	for _, a := range transport.lastEvent.Attachments {
+		filenames = append(filenames, a.Filename)
+	}
+	assertEqual(t, filenames, []string{"input.csv", "config.json", "lazy.txt"})
+}
@@ -0,0 +1,129 @@
+package sentry
+
+import (
+	"net/http"
+	"time"
+)
+
+// The constructors below return breadcrumbs of the known breadcrumb types,
+// with the Data keys expected by Sentry. The returned breadcrumbs can be
+// further customized before being passed to AddBreadcrumb.
+
+// NewHTTPBreadcrumb returns a breadcrumb describing an HTTP request. The
+// status code and reason are omitted if zero. The level is set from the
+// status code: warning for client errors and error for server errors.
+func NewHTTPBreadcrumb(method, url string, statusCode int, reason string) *Breadcrumb {
+	data := map[string]interface{}{
+		"method": method,
+		"url":    url,
+	}
+	if statusCode != 0 {
+		data["status_code"] = statusCode
+	}
+	if reason != "" {
+		data["reason"] = reason
+	}
+	level := LevelInfo
+	switch {
+	case statusCode >= http.StatusInternalServerError:
+		level = LevelError
+	case statusCode >= http.StatusBadRequest:
+		level = LevelWarning
+	}
+	return &Breadcrumb{
+		Type:      BreadcrumbTypeHTTP,
+		Category:  "http",
+		Data:      data,
+		Level:     level,
+		Timestamp: time.Now(),
+	}
+}
+
+// NewNavigationBreadcrumb returns a breadcrumb describing a change of
+// location, such as a redirect, from one URL or path to another.
+func NewNavigationBreadcrumb(from, to string) *Breadcrumb {
+	return &Breadcrumb{
+		Type:     BreadcrumbTypeNavigation,
+		Category: "navigation",
+		Data: map[string]interface{}{
+			"from": from,
+			"to":   to,
+		},
+		Level:     LevelInfo,
+		Timestamp: time.Now(),
+	}
+}
+
+// NewQueryBreadcrumb returns a breadcrumb describing a database query. The
+// category identifies the kind of query, for example "db.sql.query", and data
+// holds additional information, such as the query parameters.
+func NewQueryBreadcrumb(category, query string, data map[string]interface{}) *Breadcrumb {
+	return &Breadcrumb{
+		Type:      BreadcrumbTypeQuery,
+		Category:  category,
+		Message:   query,
+		Data:      data,
+		Level:     LevelInfo,
+		Timestamp: time.Now(),
+	}
+}
+
+// NewUserBreadcrumb returns a breadcrumb describing an action taken by the
+// user. The category identifies the action, for example "auth.login".
+func NewUserBreadcrumb(category, message string) *Breadcrumb {
+	return &Breadcrumb{
+		Type:      BreadcrumbTypeUser,
+		Category:  category,
+		Message:   message,
+		Level:     LevelInfo,
+		Timestamp: time.Now(),
+	}
+}
+
+// NewDebugBreadcrumb returns a breadcrumb with debugging information.
+func NewDebugBreadcrumb(message string) *Breadcrumb {
+	return &Breadcrumb{
+		Type:      BreadcrumbTypeDebug,
+		Message:   message,
+		Level:     LevelDebug,
+		Timestamp: time.Now(),
+	}
+}
+
+// NewInfoBreadcrumb returns a breadcrumb with information that helps to
+// understand the cause of an event.
+func NewInfoBreadcrumb(message string) *Breadcrumb {
+	return &Breadcrumb{
+		Type:      BreadcrumbTypeInfo,
+		Message:   message,
+		Level:     LevelInfo,
+		Timestamp: time.Now(),
+	}
+}
+
+// NewErrorBreadcrumb returns a breadcrumb describing an error that was not
+// reported to Sentry as an event of its own.
+func NewErrorBreadcrumb(err error) *Breadcrumb {
+	b := &Breadcrumb{
+		Type:      BreadcrumbTypeError,
+		Level:     LevelError,
+		Timestamp: time.Now(),
+	}
+	if err != nil {
+		b.Message = err.Error()
+	}
+	return b
+}
+
+// NewTransactionBreadcrumb returns a breadcrumb describing a transaction, such
+// as a request handled by a server, identified by its name.
+func NewTransactionBreadcrumb(name string, data map[string]interface{}) *Breadcrumb {
+	return &Breadcrumb{
+		Type:      BreadcrumbTypeTransaction,
+		Category:  "transaction",
+		Message:   name,
+		Data:      data,
+		Level:     LevelInfo,
+		Timestamp: time.Now(),
+	}
+}
@@ -0,0 +1,113 @@
+package sentry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestBreadcrumbConstructors(t *testing.T) {
+	tests := []struct {
+		name string
+		got  *Breadcrumb
+		want *Breadcrumb
+	}{
+		{
+			name: "HTTP",
+			got:  NewHTTPBreadcrumb("GET", "https://example.com/", 200, "OK"),
+			want: &Breadcrumb{
+				Type:     BreadcrumbTypeHTTP,
+				Category: "http",
+				Data: map[string]interface{}{
+					"method":      "GET",
+					"url":         "https://example.com/",
+					"status_code": 200,
+					"reason":      "OK",
+				},
+				Level: LevelInfo,
+			},
+		},
+		{
+			name: "HTTPServerError",
+			got:  NewHTTPBreadcrumb("POST", "https://example.com/", 503, ""),
+			want: &Breadcrumb{
+				Type:     BreadcrumbTypeHTTP,
+				Category: "http",
+				Data: map[string]interface{}{
+					"method":      "POST",
+					"url":         "https://example.com/",
+					"status_code": 503,
+				},
+				Level: LevelError,
+			},
+		},
+		{
+			name: "Navigation",
+			got:  NewNavigationBreadcrumb("/login", "/home"),
+			want: &Breadcrumb{
+				Type:     BreadcrumbTypeNavigation,
+				Category: "navigation",
+				Data:     map[string]interface{}{"from": "/login", "to": "/home"},
+				Level:    LevelInfo,
+			},
+		},
+		{
+			name: "Query",
+			got:  NewQueryBreadcrumb("db.sql.query", "SELECT 1", nil),
+			want: &Breadcrumb{
+				Type:     BreadcrumbTypeQuery,
+				Category: "db.sql.query",
+				Message:  "SELECT 1",
+				Level:    LevelInfo,
+			},
+		},
+		{
+			name: "User",
+			got:  NewUserBreadcrumb("auth.login", "user logged in"),
+			want: &Breadcrumb{
+				Type:     BreadcrumbTypeUser,
+				Category: "auth.login",
+				Message:  "user logged in",
+				Level:    LevelInfo,
+			},
+		},
+		{
+			name: "Debug",
+			got:  NewDebugBreadcrumb("cache miss"),
+			want: &Breadcrumb{Type: BreadcrumbTypeDebug, Message: "cache miss", Level: LevelDebug},
+		},
+		{
+			name: "Info",
+			got:  NewInfoBreadcrumb("job started"),
+			want: &Breadcrumb{Type: BreadcrumbTypeInfo, Message: "job started", Level: LevelInfo},
+		},
+		{
+			name: "Error",
+			got:  NewErrorBreadcrumb(errors.New("retrying")),
+			want: &Breadcrumb{Type: BreadcrumbTypeError, Message: "retrying", Level: LevelError},
+		},
+		{
+			name: "Transaction",
+			got:  NewTransactionBreadcrumb("GET /home", nil),
+			want: &Breadcrumb{
+				Type:     BreadcrumbTypeTransaction,
+				Category: "transaction",
+				Message:  "GET /home",
+				Level:    LevelInfo,
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got.Timestamp.IsZero() {
+				t.Error("breadcrumb has no timestamp")
+			}
+			if diff := cmp.Diff(tt.want, tt.got, cmpopts.IgnoreFields(Breadcrumb{}, "Timestamp")); diff != "" {
+				t.Errorf("Breadcrumb mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package sentry
 import (
 	"context"
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -10,6 +11,7 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
+	"path"
 	"reflect"
 	"sort"
 	"sync"
@@ -25,6 +27,10 @@ import (
 // stack trace is often the most useful information.
 const maxErrorDepth = 10
 
+// maxErrors is the maximum number of errors reported for a tree of errors,
+// such as errors joining several other errors.
+const maxErrors = 50
+
 // hostname is the host name reported by the kernel. It is precomputed once to
 // avoid syscalls when capturing events.
 //
@@ -110,6 +116,9 @@ type ClientOptions struct {
 	// Configures whether SDK should generate and attach stacktraces to pure
 	// capture message calls.
 	AttachStacktrace bool
+	// Configures whether the SDK should attach the stack traces of all
+	// goroutines to the events of recovered panics, as threads.
+	AttachGoroutines bool
 	// The sample rate for event submission in the range [0.0, 1.0]. By default,
 	// all events are sent. Thus, as a historical special case, the sample rate
 	// 0.0 is treated as if it was 1.0.
@@ -118,6 +127,41 @@ type ClientOptions struct {
 	// and if applicable, caught errors type and value.
 	// If the match is found, then a whole event will be dropped.
 	IgnoreErrors []string
+	// Time window in which events identical to an event already sent are
+	// dropped, to avoid flooding Sentry with events from a loop. Events are
+	// identical when they have the same exceptions, including stack traces,
+	// or the same message, and the same fingerprint and level. The number of
+	// dropped events is reported with the next event sent. Defaults to 5
+	// seconds. A negative value disables deduplication.
+	DedupeWindow time.Duration
+	// Module paths of the frames to mark as in app, along with the packages
+	// below them, such that Sentry highlights them and the SDK adds source
+	// code context to them. By default, only frames of the main module are in
+	// app. InAppInclude takes precedence over InAppExclude.
+	InAppInclude []string
+	// Module paths of the frames to mark as not in app, along with the
+	// packages below them.
+	InAppExclude []string
+	// Number of lines of source code added before and after the line of in-app
+	// stack trace frames. Defaults to 5. A negative value disables source
+	// code context.
+	ContextLines int
+	// Maximum size in bytes of the source files read to add source code
+	// context. Larger files are ignored. Defaults to 1 MB. A negative value
+	// disables the limit.
+	MaxSourceFileBytes int
+	// Reader of the source files used to add source code context to stack
+	// trace frames, for programs deployed without their source code. Use
+	// NewFSSourceFileReader to read source files embedded in the program.
+	// Defaults to reading source files from disk.
+	SourceFileReader SourceFileReader
+	// Directories of modules relative to the root of their repository, keyed
+	// by module path, for modules not at the root of their repository.
+	ModuleRepositoryDirs map[string]string
+	// Rules that set the fingerprint of the events they match, to control how
+	// events are grouped into issues. The first matching rule applies. Events
+	// with a fingerprint set in the scope or by the caller are left untouched.
+	FingerprintRules []FingerprintRule
 	// Before send callback.
 	BeforeSend func(event *Event, hint *EventHint) *Event
 	// Before breadcrumb add callback.
@@ -131,7 +175,11 @@ type ClientOptions struct {
 	Transport Transport
 	// The server name to be reported.
 	ServerName string
-	// The release to be sent with events.
+	// The release to be sent with events. If not set, the SENTRY_RELEASE
+	// environment variable is used. Otherwise, the release is detected from
+	// the version control revision embedded in the binary, the version of the
+	// main module, or the revision set by common CI and PaaS providers in the
+	// environment, such as GITHUB_SHA.
 	Release string
 	// The dist to be sent with events.
 	Dist string
@@ -139,6 +187,47 @@ type ClientOptions struct {
 	Environment string
 	// Maximum number of breadcrumbs.
 	MaxBreadcrumbs int
+	// Maximum size in bytes of an event encoded as JSON, larger events are
+	// trimmed. Defaults to 1 MB. A negative value disables the limit.
+	MaxEventBytes int
+	// Maximum total size in bytes of the breadcrumbs of an event encoded as
+	// JSON. The oldest breadcrumbs are dropped first. Defaults to 250 KB. A
+	// negative value disables the limit.
+	MaxBreadcrumbsBytes int
+	// Maximum length in bytes of the event message and of strings in the
+	// extra and contexts data. Longer strings are truncated. Defaults to 8192.
+	// A negative value disables the limit.
+	MaxStringLength int
+	// Maximum number of frames per stack trace. Frames in the middle of longer
+	// stack traces are omitted, keeping the outermost and innermost frames,
+	// and the range of omitted frames is reported to Sentry. Defaults to 100.
+	// A negative value disables the limit.
+	MaxFrames int
+	// FrameFilter reports whether to keep a frame of the stack traces captured
+	// by the SDK. Defaults to DefaultFrameFilter.
+	FrameFilter func(Frame) bool
+	// Maximum size in bytes of an attachment. Larger attachments are dropped.
+	// Defaults to 20 MB. A negative value disables the limit.
+	MaxAttachmentBytes int
+	// Send personally identifiable information with events. When disabled,
+	// the default, cookies, authentication headers and client IP addresses
+	// are removed from requests, and emails and IP addresses from users.
+	SendDefaultPII bool
+	// Names of request headers to remove from events, in addition to the ones
+	// removed when SendDefaultPII is disabled. Names are case-insensitive.
+	DenyHeaders []string
+	// Redact sensitive data from events before they are sent, after
+	// BeforeSend. The built-in rules returned by DefaultScrubRules redact
+	// credit card numbers, email addresses, IBANs, JSON Web Tokens and fields
+	// named like password, secret or token.
+	ScrubData bool
+	// Additional rules used to redact data when ScrubData is enabled.
+	ScrubRules []ScrubRule
+	// MaxRequestBodySize controls the size of the request bodies sent with
+	// events of HTTP requests: RequestBodySizeNever, RequestBodySizeSmall,
+	// RequestBodySizeMedium or RequestBodySizeAlways. Defaults to
+	// RequestBodySizeMedium. Bodies larger than the limit are not sent at all.
+	MaxRequestBodySize RequestBodySize
 	// An optional pointer to http.Client that will be used with a default
 	// HTTPTransport. Using your own client will make HTTPTransport, HTTPProxy,
 	// HTTPSProxy and CaCerts options ignored.
@@ -164,8 +253,12 @@ type Client struct {
 	options         ClientOptions
 	dsn             *Dsn
 	eventProcessors []EventProcessor
-	integrations    []Integration
-	Transport       Transport
+	// finalEventProcessors run after BeforeSend, right before events are
+	// sent.
+	finalEventProcessors []finalEventProcessor
+	fingerprintRules     []*fingerprintRule
+	integrations         []Integration
+	Transport            Transport
 }
 
 // NewClient creates and returns an instance of Client configured using ClientOptions.
@@ -186,6 +279,13 @@ func NewClient(options ClientOptions) (*Client, error) {
 		options.Release = os.Getenv("SENTRY_RELEASE")
 	}
 
+	if options.Release == "" {
+		options.Release = defaultRelease()
+		if options.Release != "" {
+			Logger.Printf("Release detected: %s", options.Release)
+		}
+	}
+
 	if options.Environment == "" {
 		options.Environment = os.Getenv("SENTRY_ENVIRONMENT")
 	}
@@ -200,8 +300,9 @@ func NewClient(options ClientOptions) (*Client, error) {
 	}
 
 	client := Client{
-		options: options,
-		dsn:     dsn,
+		options:          options,
+		dsn:              dsn,
+		fingerprintRules: compileFingerprintRules(options.FingerprintRules),
 	}
 
 	client.setupTransport()
@@ -231,6 +332,8 @@ func (client *Client) setupIntegrations() {
 		new(environmentIntegration),
 		new(modulesIntegration),
 		new(ignoreErrorsIntegration),
+		new(dedupeIntegration),
+		new(dataScrubbingIntegration),
 	}
 
 	if client.options.Integrations != nil {
@@ -253,6 +356,72 @@ func (client *Client) AddEventProcessor(processor EventProcessor) {
 	client.eventProcessors = append(client.eventProcessors, processor)
 }
 
+// frameFilter returns the function selecting the frames of the stack traces
+// captured by the client.
+func (client *Client) frameFilter() func(Frame) bool {
+	if filter := client.Options().FrameFilter; filter != nil {
+		return filter
+	}
+	return DefaultFrameFilter
+}
+
+// prepareFrames applies the options on stack trace frames to the event. Frames
+// are limited before event processors run, such that no work is spent on
+// frames that are not sent. FrameFilter applies earlier, when stack traces are
+// captured, such that the frames omitted by MaxFrames are counted in the
+// frames of the captured stack traces.
+func (client *Client) prepareFrames(event *Event) {
+	options := client.Options()
+	if len(options.InAppInclude) > 0 || len(options.InAppExclude) > 0 {
+		for _, stacktrace := range eventStacktraces(event) {
+			setInAppFrames(stacktrace.Frames, options.InAppInclude, options.InAppExclude)
+		}
+	}
+	if max := limit(options.MaxFrames, defaultMaxFrames); max > 0 {
+		trimEventFrames(event, max)
+	}
+}
+
+// setRepositoryFilenames makes the file names of the frames of the event
+// relative to the root of the repository of their module instead of the root
+// of the module. It runs after event processors, which read source files from
+// their path in the module.
+func (client *Client) setRepositoryFilenames(event *Event) {
+	dirs := client.Options().ModuleRepositoryDirs
+	for _, stacktrace := range eventStacktraces(event) {
+		for i := range stacktrace.Frames {
+			frame := &stacktrace.Frames[i]
+			if frame.Package == "" || frame.Filename == "" {
+				continue
+			}
+			dir, ok := dirs[frame.Package]
+			if !ok {
+				dir = repositoryDir(frame.Package)
+			}
+			if dir != "" {
+				frame.Filename = path.Join(dir, frame.Filename)
+			}
+		}
+	}
+}
+
+// A finalEventProcessor is an event processor that runs after BeforeSend.
+// Events it drops are counted in metrics under dropReason.
+type finalEventProcessor struct {
+	process    EventProcessor
+	dropReason string
+}
+
+// addFinalEventProcessor adds an event processor that runs last, after
+// BeforeSend, such that it sees the final event. Events dropped by the
+// processor are counted in metrics under dropReason.
+func (client *Client) addFinalEventProcessor(processor EventProcessor, dropReason string) {
+	client.finalEventProcessors = append(client.finalEventProcessors, finalEventProcessor{
+		process:    processor,
+		dropReason: dropReason,
+	})
+}
+
 // Options return ClientOptions for the current Client.
 func (client Client) Options() ClientOptions {
 	return client.options
@@ -264,6 +433,14 @@ func (client *Client) CaptureMessage(message string, hint *EventHint, scope Even
 	return client.CaptureEvent(event, hint, scope)
 }
 
+// CaptureMessageTemplate captures a message formatted from a template and
+// parameters, as with fmt.Sprintf. Events are grouped by the template instead
+// of by the formatted message.
+func (client *Client) CaptureMessageTemplate(template string, params []interface{}, hint *EventHint, scope EventModifier) *EventID {
+	event := client.eventFromMessageTemplate(template, params, LevelInfo)
+	return client.CaptureEvent(event, hint, scope)
+}
+
 // CaptureException captures an error.
 func (client *Client) CaptureException(exception error, hint *EventHint, scope EventModifier) *EventID {
 	event := client.eventFromException(exception, LevelError)
@@ -295,6 +472,7 @@ func (client *Client) Recover(err interface{}, hint *EventHint, scope EventModif
 }
 
 // RecoverWithContext captures a panic and passes relevant context object.
+// Panics with an error value are reported as unhandled exceptions.
 // Returns EventID if successfully, or nil if there's no error to recover from.
 func (client *Client) RecoverWithContext(
 	ctx context.Context,
@@ -319,14 +497,20 @@ func (client *Client) RecoverWithContext(
 	}
 
 	var event *Event
-	switch err := err.(type) {
-	case error:
-		event = client.eventFromException(err, LevelFatal)
-	case string:
-		event = client.eventFromMessage(err, LevelFatal)
-	default:
-		event = client.eventFromMessage(fmt.Sprintf("%#v", err), LevelFatal)
+	if e, ok := err.(error); ok {
+		event = client.eventFromException(e, LevelFatal)
+	} else {
+		event = client.eventFromPanicValue(err)
 	}
+	setMechanism(event, MechanismTypePanic, false)
+
+	if client.Options().AttachGoroutines {
+		event.Threads = goroutineThreads(client.frameFilter())
+		if len(event.Threads) > 0 && len(event.Exception) > 0 {
+			event.Exception[len(event.Exception)-1].ThreadID = event.Threads[0].ID
+		}
+	}
+
 	return client.CaptureEvent(event, hint, scope)
 }
 
@@ -356,7 +540,7 @@ func (client *Client) eventFromMessage(message string, level Level) *Event {
 
 	if client.Options().AttachStacktrace {
 		event.Threads = []Thread{{
-			Stacktrace: NewStacktrace(),
+			Stacktrace: newStacktrace(client.frameFilter()),
 			Crashed:    false,
 			Current:    true,
 		}}
@@ -365,6 +549,62 @@ func (client *Client) eventFromMessage(message string, level Level) *Event {
 	return event
 }
 
+// eventFromPanicValue returns an event with a synthetic exception for a panic
+// whose value is not an error, as in panic("message"). The value of the
+// exception is the string, or the Go syntax representation of other values.
+func (client *Client) eventFromPanicValue(v interface{}) *Event {
+	value, ok := v.(string)
+	if !ok {
+		value = fmt.Sprintf("%#v", v)
+	}
+	event := NewEvent()
+	event.Level = LevelFatal
+	event.Exception = []Exception{{
+		Type:       "panic",
+		Value:      value,
+		Stacktrace: newStacktrace(client.frameFilter()),
+	}}
+	return event
+}
+
+func (client *Client) eventFromMessageTemplate(template string, params []interface{}, level Level) *Event {
+	if template == "" {
+		err := usageError{fmt.Errorf("%s called with empty template", callerFunctionName())}
+		return client.eventFromException(err, level)
+	}
+	event := client.eventFromMessage(fmt.Sprintf(template, params...), level)
+	event.LogEntry = &LogEntry{
+		Message:   template,
+		Params:    logEntryParams(params),
+		Formatted: event.Message,
+	}
+	return event
+}
+
+// logEntryParams returns a copy of params in which values that cannot be
+// encoded as JSON are replaced by their default string representation.
+func logEntryParams(params []interface{}) []interface{} {
+	if len(params) == 0 {
+		return nil
+	}
+	p := make([]interface{}, len(params))
+	for i, v := range params {
+		switch v := v.(type) {
+		case error:
+			p[i] = v.Error()
+		case fmt.Stringer:
+			p[i] = v.String()
+		default:
+			if _, err := json.Marshal(v); err != nil {
+				p[i] = fmt.Sprintf("%v", v)
+			} else {
+				p[i] = v
+			}
+		}
+	}
+	return p
+}
+
 func (client *Client) eventFromException(exception error, level Level) *Event {
 	err := exception
 	if err == nil {
@@ -374,36 +614,155 @@ func (client *Client) eventFromException(exception error, level Level) *Event {
 	event := NewEvent()
 	event.Level = level
 
-	for i := 0; i < maxErrorDepth && err != nil; i++ {
-		event.Exception = append(event.Exception, Exception{
-			Value:      err.Error(),
-			Type:       reflect.TypeOf(err).String(),
-			Stacktrace: ExtractStacktrace(err),
-		})
-		switch previous := err.(type) {
-		case interface{ Unwrap() error }:
-			err = previous.Unwrap()
-		case interface{ Cause() error }:
-			err = previous.Cause()
-		default:
-			err = nil
-		}
-	}
+	var errs []error
+	event.Exception, errs = exceptionsFromError(err, client.frameFilter())
 
 	// Add a trace of the current stack to the most recent error in a chain if
 	// it doesn't have a stack trace yet.
 	// We only add to the most recent error to avoid duplication and because the
 	// current stack is most likely unrelated to errors deeper in the chain.
 	if event.Exception[0].Stacktrace == nil {
-		event.Exception[0].Stacktrace = NewStacktrace()
+		event.Exception[0].Stacktrace = newStacktrace(client.frameFilter())
 	}
 
 	// event.Exception should be sorted such that the most recent error is last.
 	reverse(event.Exception)
 
+	setMechanism(event, MechanismTypeGeneric, true)
+
+	applyErrorMetadata(event, errs)
+
 	return event
 }
 
+// exceptionsFromError walks the tree of errors wrapped by err depth-first and
+// returns an exception for each error, starting with err itself. Errors are
+// unwrapped with Unwrap() error, Cause() error or, for errors that join
+// several errors, Unwrap() []error. The walked errors are returned too, in the
+// same order. The stack traces of the exceptions keep the frames for which keep
+// returns true.
+//
+// Each exception is linked to the error it was unwrapped from through the
+// exception and parent IDs of its mechanism. Errors that wrap themselves,
+// directly or indirectly, are reported only once.
+func exceptionsFromError(err error, keep func(Frame) bool) ([]Exception, []error) {
+	var exceptions []Exception
+	var errs []error
+	// ancestors holds the pointer errors in the path being walked, to detect
+	// cycles.
+	ancestors := make(map[uintptr]bool)
+
+	var walk func(err error, parentID *int, source string, depth int)
+	walk = func(err error, parentID *int, source string, depth int) {
+		if err == nil || depth >= maxErrorDepth || len(exceptions) >= maxErrors {
+			return
+		}
+		if v := reflect.ValueOf(err); v.Kind() == reflect.Ptr {
+			p := v.Pointer()
+			if ancestors[p] {
+				return
+			}
+			ancestors[p] = true
+			defer delete(ancestors, p)
+		}
+
+		id := len(exceptions)
+		mechanism := &Mechanism{
+			Type:        MechanismTypeChained,
+			ExceptionID: id,
+			ParentID:    parentID,
+			Source:      source,
+		}
+		errs = append(errs, err)
+		exceptions = append(exceptions, Exception{
+			Value:      err.Error(),
+			Type:       reflect.TypeOf(err).String(),
+			Stacktrace: extractStacktrace(err, keep),
+			Mechanism:  mechanism,
+		})
+
+		switch err := err.(type) {
+		case interface{ Unwrap() []error }:
+			mechanism.IsExceptionGroup = true
+			for i, e := range err.Unwrap() {
+				walk(e, &id, fmt.Sprintf("errors[%d]", i), depth+1)
+			}
+		case interface{ Unwrap() error }:
+			walk(err.Unwrap(), &id, "", depth+1)
+		case interface{ Cause() error }:
+			walk(err.Cause(), &id, "", depth+1)
+		}
+	}
+	walk(err, nil, "", 0)
+
+	return exceptions, errs
+}
+
+// applyErrorMetadata sets event data carried by errors that implement any of
+// the methods below:
+//
+//	SentryTags() map[string]string
+//	SentryContext() map[string]interface{}
+//	SentryFingerprint() []string
+//	SentryLevel() Level
+//	SentryUser() User
+//
+// Errors are given in the order returned by exceptionsFromError, outermost
+// first. Data of outer errors takes precedence over data of the errors they
+// wrap. SentryContext returns contexts by name, merged into Event.Contexts.
+func applyErrorMetadata(event *Event, errs []error) {
+	for i := len(errs) - 1; i >= 0; i-- {
+		err := errs[i]
+		if e, ok := err.(interface{ SentryTags() map[string]string }); ok {
+			tags := e.SentryTags()
+			if len(tags) > 0 && event.Tags == nil {
+				event.Tags = make(map[string]string, len(tags))
+			}
+			for k, v := range tags {
+				event.Tags[k] = v
+			}
+		}
+		if e, ok := err.(interface{ SentryContext() map[string]interface{} }); ok {
+			contexts := e.SentryContext()
+			if len(contexts) > 0 && event.Contexts == nil {
+				event.Contexts = make(map[string]interface{}, len(contexts))
+			}
+			for k, v := range contexts {
+				event.Contexts[k] = v
+			}
+		}
+		if e, ok := err.(interface{ SentryFingerprint() []string }); ok {
+			if fingerprint := e.SentryFingerprint(); len(fingerprint) > 0 {
+				event.Fingerprint = fingerprint
+			}
+		}
+		if e, ok := err.(interface{ SentryLevel() Level }); ok {
+			if level := e.SentryLevel(); level != "" {
+				event.Level = level
+			}
+		}
+		if e, ok := err.(interface{ SentryUser() User }); ok {
+			if user := e.SentryUser(); user != (User{}) {
+				event.User = user
+			}
+		}
+	}
+}
+
+// setMechanism sets the type of the mechanism of the most recent exception in
+// the event and whether the exception was handled.
+func setMechanism(event *Event, mechanismType string, handled bool) {
+	if len(event.Exception) == 0 {
+		return
+	}
+	ex := &event.Exception[len(event.Exception)-1]
+	if ex.Mechanism == nil {
+		ex.Mechanism = &Mechanism{}
+	}
+	ex.Mechanism.Type = mechanismType
+	ex.Mechanism.Handled = &handled
+}
+
 // reverse reverses the slice a in place.
 func reverse(a []Exception) {
 	for i := len(a)/2 - 1; i >= 0; i-- {
@@ -412,12 +771,17 @@ func reverse(a []Exception) {
 	}
 }
 
+// processEvent prepares, filters and trims the event before sending it. The
+// event may share maps, slices and pointers with the scope and the caller, so
+// the SDK copies them rather than modifying them in place.
 func (client *Client) processEvent(event *Event, hint *EventHint, scope EventModifier) *EventID {
 	if event == nil {
 		err := usageError{fmt.Errorf("%s called with nil event", callerFunctionName())}
 		return client.CaptureException(err, hint, scope)
 	}
 
+	metrics.eventsCaptured.Add(1)
+
 	options := client.Options()
 
 	// TODO: Reconsider if its worth going away from default implementation
@@ -428,11 +792,13 @@ func (client *Client) processEvent(event *Event, hint *EventHint, scope EventMod
 		randomFloat := rng.Float64()
 		if randomFloat > options.SampleRate {
 			Logger.Println("Event dropped due to SampleRate hit.")
+			metrics.dropped(dropReasonSampleRate)
 			return nil
 		}
 	}
 
 	if event = client.prepareEvent(event, hint, scope); event == nil {
+		metrics.dropped(dropReasonEventProcessor)
 		return nil
 	}
 
@@ -443,10 +809,23 @@ func (client *Client) processEvent(event *Event, hint *EventHint, scope EventMod
 		}
 		if event = options.BeforeSend(event, hint); event == nil {
 			Logger.Println("Event dropped due to BeforeSend callback.")
+			metrics.dropped(dropReasonBeforeSend)
+			return nil
+		}
+	}
+
+	for _, processor := range client.finalEventProcessors {
+		id := event.EventID
+		if event = processor.process(event, hint); event == nil {
+			Logger.Printf("Event dropped by one of the final EventProcessors: %s\n", id)
+			metrics.dropped(processor.dropReason)
 			return nil
 		}
 	}
 
+	client.trimEvent(event)
+	client.loadAttachments(event)
+
 	client.Transport.SendEvent(event)
 
 	return &event.EventID
@@ -496,6 +875,10 @@ func (client *Client) prepareEvent(event *Event, hint *EventHint, scope EventMod
 		}},
 	}
 
+	if hint != nil && len(hint.Attachments) > 0 {
+		event.Attachments = append(event.Attachments, hint.Attachments...)
+	}
+
 	if scope != nil {
 		event = scope.ApplyToEvent(event, hint)
 		if event == nil {
@@ -503,6 +886,8 @@ func (client *Client) prepareEvent(event *Event, hint *EventHint, scope EventMod
 		}
 	}
 
+	client.prepareFrames(event)
+
 	for _, processor := range client.eventProcessors {
 		id := event.EventID
 		event = processor(event, hint)
@@ -521,6 +906,10 @@ func (client *Client) prepareEvent(event *Event, hint *EventHint, scope EventMod
 		}
 	}
 
+	client.setRepositoryFilenames(event)
+	applyFingerprintRules(event, client.fingerprintRules)
+	removePII(event, client.Options().SendDefaultPII, client.Options().DenyHeaders)
+
 	return event
 }
 
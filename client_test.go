@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -73,6 +74,7 @@ func TestCaptureMessageEmptyString(t *testing.T) {
 				Type:       "sentry.usageError",
 				Value:      "CaptureMessage called with empty message",
 				Stacktrace: &Stacktrace{Frames: []Frame{}},
+				Mechanism:  handledMechanism(),
 			},
 		},
 	}
@@ -87,6 +89,31 @@ func TestCaptureMessageEmptyString(t *testing.T) {
 	}
 }
 
+func TestCaptureMessageTemplate(t *testing.T) {
+	client, scope, transport := setupClientTest()
+	client.CaptureMessageTemplate("user %d failed to %s", []interface{}{42, "login"}, nil, scope)
+	assertEqual(t, transport.lastEvent.Message, "user 42 failed to login")
+	assertEqual(t, transport.lastEvent.LogEntry, &LogEntry{
+		Message:   "user %d failed to %s",
+		Params:    []interface{}{42, "login"},
+		Formatted: "user 42 failed to login",
+	})
+}
+
+func TestCaptureMessageTemplateUnencodableParams(t *testing.T) {
+	client, scope, transport := setupClientTest()
+	ch := make(chan int)
+	client.CaptureMessageTemplate("%v %v %v", []interface{}{errors.New("oops"), time.Second, ch}, nil, scope)
+	assertEqual(t, transport.lastEvent.LogEntry.Params, []interface{}{"oops", "1s", fmt.Sprintf("%v", ch)})
+}
+
+func TestCaptureMessageTemplateEmptyString(t *testing.T) {
+	client, scope, transport := setupClientTest()
+	client.CaptureMessageTemplate("", nil, nil, scope)
+	assertEqual(t, transport.lastEvent.LogEntry, (*LogEntry)(nil))
+	assertEqual(t, transport.lastEvent.Exception[0].Value, "CaptureMessageTemplate called with empty template")
+}
+
 type customErr struct{}
 
 func (e *customErr) Error() string {
@@ -113,6 +140,48 @@ func (e wrappedError) Unwrap() error {
 	return e.original
 }
 
+// joinedError joins several errors, like errors.Join in Go 1.20+.
+type joinedError []error
+
+func (e joinedError) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+func (e joinedError) Unwrap() []error {
+	return e
+}
+
+type cyclicError struct{ next error }
+
+func (e *cyclicError) Error() string {
+	return "cycle"
+}
+
+func (e *cyclicError) Unwrap() error {
+	return e.next
+}
+
+func handledMechanism() *Mechanism {
+	handled := true
+	return &Mechanism{Type: MechanismTypeGeneric, Handled: &handled}
+}
+
+func unhandledMechanism() *Mechanism {
+	handled := false
+	return &Mechanism{Type: MechanismTypePanic, Handled: &handled}
+}
+
+func chainedMechanism(id, parentID int) *Mechanism {
+	return &Mechanism{Type: MechanismTypeChained, ExceptionID: id, ParentID: &parentID}
+}
+
+func intPtr(i int) *int    { return &i }
+func boolPtr(b bool) *bool { return &b }
+
 type captureExceptionTestGroup struct {
 	name  string
 	tests []captureExceptionTest
@@ -134,6 +203,7 @@ func TestCaptureException(t *testing.T) {
 					Type:       "sentry.usageError",
 					Value:      "CaptureException called with nil error",
 					Stacktrace: &Stacktrace{Frames: []Frame{}},
+					Mechanism:  handledMechanism(),
 				},
 			},
 		},
@@ -145,6 +215,7 @@ func TestCaptureException(t *testing.T) {
 					Type:       "*errors.errorString",
 					Value:      "custom error",
 					Stacktrace: &Stacktrace{Frames: []Frame{}},
+					Mechanism:  handledMechanism(),
 				},
 			},
 		},
@@ -161,11 +232,13 @@ func TestCaptureException(t *testing.T) {
 					// No Stacktrace, because we can't tell where the error came
 					// from and because we have a stack trace in the most recent
 					// error in the chain.
+					Mechanism: chainedMechanism(1, 0),
 				},
 				{
 					Type:       "*errors.withStack",
 					Value:      "wat",
 					Stacktrace: &Stacktrace{Frames: []Frame{}},
+					Mechanism:  handledMechanism(),
 				},
 			},
 		},
@@ -177,6 +250,7 @@ func TestCaptureException(t *testing.T) {
 					Type:       "*sentry.customErrWithCause",
 					Value:      "err",
 					Stacktrace: &Stacktrace{Frames: []Frame{}},
+					Mechanism:  handledMechanism(),
 				},
 			},
 		},
@@ -185,13 +259,15 @@ func TestCaptureException(t *testing.T) {
 			err:  &customErrWithCause{cause: &customErr{}},
 			want: []Exception{
 				{
-					Type:  "*sentry.customErr",
-					Value: "wat",
+					Type:      "*sentry.customErr",
+					Value:     "wat",
+					Mechanism: chainedMechanism(1, 0),
 				},
 				{
 					Type:       "*sentry.customErrWithCause",
 					Value:      "err",
 					Stacktrace: &Stacktrace{Frames: []Frame{}},
+					Mechanism:  handledMechanism(),
 				},
 			},
 		},
@@ -200,13 +276,79 @@ func TestCaptureException(t *testing.T) {
 			err:  wrappedError{original: errors.New("original")},
 			want: []Exception{
 				{
-					Type:  "*errors.errorString",
-					Value: "original",
+					Type:      "*errors.errorString",
+					Value:     "original",
+					Mechanism: chainedMechanism(1, 0),
 				},
 				{
 					Type:       "sentry.wrappedError",
 					Value:      "wrapped: original",
 					Stacktrace: &Stacktrace{Frames: []Frame{}},
+					Mechanism:  handledMechanism(),
+				},
+			},
+		},
+	}
+
+	cyclic := &cyclicError{}
+	cyclic.next = wrappedError{original: cyclic}
+
+	errorTreeTests := []captureExceptionTest{
+		{
+			name: "JoinedErrors",
+			err:  joinedError{errors.New("a"), wrappedError{original: errors.New("b")}},
+			want: []Exception{
+				{
+					Type:      "*errors.errorString",
+					Value:     "b",
+					Mechanism: chainedMechanism(3, 2),
+				},
+				{
+					Type:  "sentry.wrappedError",
+					Value: "wrapped: b",
+					Mechanism: &Mechanism{
+						Type:        MechanismTypeChained,
+						ExceptionID: 2,
+						ParentID:    intPtr(0),
+						Source:      "errors[1]",
+					},
+				},
+				{
+					Type:  "*errors.errorString",
+					Value: "a",
+					Mechanism: &Mechanism{
+						Type:        MechanismTypeChained,
+						ExceptionID: 1,
+						ParentID:    intPtr(0),
+						Source:      "errors[0]",
+					},
+				},
+				{
+					Type:       "sentry.joinedError",
+					Value:      "a\nwrapped: b",
+					Stacktrace: &Stacktrace{Frames: []Frame{}},
+					Mechanism: &Mechanism{
+						Type:             MechanismTypeGeneric,
+						Handled:          boolPtr(true),
+						IsExceptionGroup: true,
+					},
+				},
+			},
+		},
+		{
+			name: "Cycle",
+			err:  cyclic,
+			want: []Exception{
+				{
+					Type:      "sentry.wrappedError",
+					Value:     "wrapped: cycle",
+					Mechanism: chainedMechanism(1, 0),
+				},
+				{
+					Type:       "*sentry.cyclicError",
+					Value:      "cycle",
+					Stacktrace: &Stacktrace{Frames: []Frame{}},
+					Mechanism:  handledMechanism(),
 				},
 			},
 		},
@@ -221,6 +363,10 @@ func TestCaptureException(t *testing.T) {
 			name:  "ErrorChain",
 			tests: errorChainTests,
 		},
+		{
+			name:  "ErrorTree",
+			tests: errorTreeTests,
+		},
 	}
 
 	for _, grp := range tests {
@@ -241,6 +387,170 @@ func TestCaptureException(t *testing.T) {
 	}
 }
 
+type orderError struct {
+	orderID string
+	err     error
+}
+
+func (e *orderError) Error() string                 { return "order " + e.orderID + ": " + e.err.Error() }
+func (e *orderError) Unwrap() error                 { return e.err }
+func (e *orderError) SentryTags() map[string]string { return map[string]string{"order_id": e.orderID} }
+func (e *orderError) SentryContext() map[string]interface{} {
+	return map[string]interface{}{"order": map[string]interface{}{"id": e.orderID}}
+}
+
+type tenantError struct{ tenant string }
+
+func (e *tenantError) Error() string { return "tenant " + e.tenant + " suspended" }
+func (e *tenantError) SentryTags() map[string]string {
+	return map[string]string{"tenant": e.tenant, "order_id": "inner"}
+}
+func (e *tenantError) SentryFingerprint() []string { return []string{"tenant-suspended"} }
+func (e *tenantError) SentryLevel() Level          { return LevelWarning }
+func (e *tenantError) SentryUser() User            { return User{ID: e.tenant} }
+
+func TestCaptureExceptionErrorMetadata(t *testing.T) {
+	client, scope, transport := setupClientTest()
+	err := &orderError{orderID: "42", err: &tenantError{tenant: "acme"}}
+	client.CaptureException(err, nil, scope)
+
+	event := transport.lastEvent
+	if event == nil {
+		t.Fatal("missing event")
+	}
+	assertEqual(t, event.Tags, map[string]string{"order_id": "42", "tenant": "acme"})
+	assertEqual(t, event.Contexts["order"], map[string]interface{}{"id": "42"})
+	assertEqual(t, event.Fingerprint, []string{"tenant-suspended"})
+	assertEqual(t, event.Level, LevelWarning)
+	assertEqual(t, event.User, User{ID: "acme"})
+}
+
+func TestInAppOptions(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{
+		Transport:    transport,
+		InAppInclude: []string{"github.com/pkg/errors"},
+		InAppExclude: []string{"example.com/app/generated"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := NewEvent()
+	event.Exception = []Exception{{Stacktrace: &Stacktrace{Frames: []Frame{
+		{Module: "example.com/app/generated", InApp: true},
+		{Module: "github.com/pkg/errors", InApp: false},
+	}}}}
+	client.CaptureEvent(event, nil, nil)
+
+	frames := transport.lastEvent.Exception[0].Stacktrace.Frames
+	assertEqual(t, frames[0].InApp, false)
+	assertEqual(t, frames[1].InApp, true)
+}
+
+func TestMaxFrames(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{
+		Transport: transport,
+		MaxFrames: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var framesSeen int
+	client.AddEventProcessor(func(event *Event, hint *EventHint) *Event {
+		framesSeen = len(event.Exception[0].Stacktrace.Frames)
+		return event
+	})
+
+	frames := []Frame{{Function: "main"}}
+	for i := 0; i < 10; i++ {
+		frames = append(frames, Frame{Function: "recurse", Lineno: i})
+	}
+	event := NewEvent()
+	event.Exception = []Exception{{Stacktrace: &Stacktrace{Frames: frames}}}
+	client.CaptureEvent(event, nil, nil)
+
+	assertEqual(t, framesSeen, 4)
+	st := transport.lastEvent.Exception[0].Stacktrace
+	assertEqual(t, st.Frames, []Frame{
+		{Function: "main"},
+		{Function: "recurse", Lineno: 0},
+		{Function: "recurse", Lineno: 8},
+		{Function: "recurse", Lineno: 9},
+	})
+	assertEqual(t, st.FramesOmitted, []uint{2, 9})
+}
+
+func TestModuleRepositoryDirs(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{
+		Transport: transport,
+		ModuleRepositoryDirs: map[string]string{
+			"example.com/app": "services/app",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := NewEvent()
+	event.Exception = []Exception{{Stacktrace: &Stacktrace{Frames: []Frame{
+		{Package: "example.com/app", Filename: "db/pool.go"},
+		{Package: "github.com/org/repo/sub", Filename: "errors.go"},
+		{Package: "github.com/org/repo", Filename: "errors.go"},
+		{Filename: "runtime/panic.go"},
+	}}}}
+	client.CaptureEvent(event, nil, nil)
+
+	var got []string
+	for _, frame := range transport.lastEvent.Exception[0].Stacktrace.Frames {
+		got = append(got, frame.Filename)
+	}
+	assertEqual(t, got, []string{"services/app/db/pool.go", "sub/errors.go", "errors.go", "runtime/panic.go"})
+}
+
+func TestFrameFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter func(Frame) bool
+		want   []string
+	}{
+		{
+			name: "ExtendDefault",
+			filter: func(frame Frame) bool {
+				return frame.Module == "testing" || DefaultFrameFilter(frame)
+			},
+			want: []string{"testing"},
+		},
+		{
+			// The frames of the functions capturing the stack trace, the
+			// test function among them, are left out nonetheless.
+			name:   "KeepAll",
+			filter: func(Frame) bool { return true },
+			want:   []string{"runtime", "testing"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &TransportMock{}
+			client, err := NewClient(ClientOptions{
+				Transport:   transport,
+				FrameFilter: tt.filter,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			client.CaptureException(errors.New("boom"), nil, nil)
+
+			var got []string
+			for _, frame := range transport.lastEvent.Exception[0].Stacktrace.Frames {
+				got = append(got, frame.Module)
+			}
+			assertEqual(t, got, tt.want)
+		})
+	}
+}
+
 func TestCaptureEvent(t *testing.T) {
 	client, _, transport := setupClientTest()
 
@@ -303,6 +613,7 @@ func TestCaptureEventNil(t *testing.T) {
 				Type:       "sentry.usageError",
 				Value:      "CaptureEvent called with nil event",
 				Stacktrace: &Stacktrace{Frames: []Frame{}},
+				Mechanism:  handledMechanism(),
 			},
 		},
 	}
@@ -442,6 +753,21 @@ func BenchmarkProcessEvent(b *testing.B) {
 	}
 }
 
+// panicEvent returns the event expected for a panic with a value that is not
+// an error.
+func panicEvent(value string) *Event {
+	return &Event{
+		Exception: []Exception{
+			{
+				Type:       "panic",
+				Value:      value,
+				Stacktrace: &Stacktrace{Frames: []Frame{}},
+				Mechanism:  unhandledMechanism(),
+			},
+		},
+	}
+}
+
 func TestRecover(t *testing.T) {
 	tests := []struct {
 		v    interface{} // for panic(v)
@@ -455,15 +781,16 @@ func TestRecover(t *testing.T) {
 						Type:       "*errors.errorString",
 						Value:      "panic error",
 						Stacktrace: &Stacktrace{Frames: []Frame{}},
+						Mechanism:  unhandledMechanism(),
 					},
 				},
 			},
 		},
-		{"panic string", &Event{Message: "panic string"}},
+		{"panic string", panicEvent("panic string")},
 		// Arbitrary types should be converted to string:
-		{101010, &Event{Message: "101010"}},
-		{[]string{"", "", "hello"}, &Event{Message: `[]string{"", "", "hello"}`}},
-		{&struct{ Field string }{"test"}, &Event{Message: `&struct { Field string }{Field:"test"}`}},
+		{101010, panicEvent("101010")},
+		{[]string{"", "", "hello"}, panicEvent(`[]string{"", "", "hello"}`)},
+		{&struct{ Field string }{"test"}, panicEvent(`&struct { Field string }{Field:"test"}`)},
 	}
 	checkEvent := func(t *testing.T, events []*Event, want *Event) {
 		t.Helper()
@@ -0,0 +1,150 @@
+package sentry
+
+import (
+	"container/list"
+	"hash/fnv"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Defaults of the Dedupe integration.
+const (
+	defaultDedupeWindow = 5 * time.Second
+	// maxDedupeEntries bounds the number of recently sent events remembered.
+	maxDedupeEntries = 1000
+)
+
+// dedupeExtraKey is the key of Event.Extra holding the number of duplicate
+// events dropped since the previous event was sent.
+const dedupeExtraKey = "sentry:duplicates_dropped"
+
+// ================================
+// Dedupe Integration
+// ================================
+
+type dedupeIntegration struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu sync.Mutex
+	// seen maps the keys of recently sent events to their elements in
+	// order, which holds the events least recently sent first.
+	seen  map[uint64]*list.Element
+	order *list.List
+	// dropped is the number of duplicates dropped since the last event was
+	// sent.
+	dropped int
+}
+
+type dedupeEntry struct {
+	key  uint64
+	sent time.Time
+}
+
+func (di *dedupeIntegration) Name() string {
+	return "Dedupe"
+}
+
+func (di *dedupeIntegration) SetupOnce(client *Client) {
+	if client.Options().DedupeWindow < 0 {
+		return
+	}
+	di.window = client.Options().DedupeWindow
+	if di.window == 0 {
+		di.window = defaultDedupeWindow
+	}
+	di.now = time.Now
+	di.seen = make(map[uint64]*list.Element)
+	di.order = list.New()
+	client.addFinalEventProcessor(di.processor, dropReasonDuplicate)
+}
+
+func (di *dedupeIntegration) processor(event *Event, hint *EventHint) *Event {
+	key, ok := dedupeKey(event)
+	if !ok {
+		return event
+	}
+
+	di.mu.Lock()
+	defer di.mu.Unlock()
+
+	now := di.now()
+	// Forget events sent before the window.
+	for e := di.order.Front(); e != nil; e = di.order.Front() {
+		if now.Sub(e.Value.(dedupeEntry).sent) < di.window {
+			break
+		}
+		delete(di.seen, di.order.Remove(e).(dedupeEntry).key)
+	}
+
+	if _, ok := di.seen[key]; ok {
+		di.dropped++
+		Logger.Println("Event dropped as a duplicate of an event sent recently.")
+		return nil
+	}
+
+	if di.order.Len() >= maxDedupeEntries {
+		delete(di.seen, di.order.Remove(di.order.Front()).(dedupeEntry).key)
+	}
+	di.seen[key] = di.order.PushBack(dedupeEntry{key: key, sent: now})
+
+	if di.dropped > 0 {
+		if event.Extra == nil {
+			event.Extra = make(map[string]interface{})
+		}
+		event.Extra[dedupeExtraKey] = di.dropped
+		di.dropped = 0
+	}
+
+	return event
+}
+
+// dedupeKey returns a hash of the fingerprint and level of the event and of
+// its exceptions, types, values and stack traces, or of its message if it has
+// no exceptions. It returns false for transactions and events with neither
+// exceptions nor a message.
+func dedupeKey(event *Event) (uint64, bool) {
+	if event.Type == transactionType {
+		return 0, false
+	}
+
+	h := fnv.New64a()
+	write := func(s string) {
+		_, _ = io.WriteString(h, s)
+		_, _ = h.Write([]byte{0})
+	}
+
+	for _, s := range event.Fingerprint {
+		write(s)
+	}
+	write(string(event.Level))
+
+	switch {
+	case len(event.Exception) > 0:
+		for _, ex := range event.Exception {
+			write(ex.Type)
+			write(ex.Value)
+			if ex.Stacktrace == nil {
+				continue
+			}
+			for _, frame := range ex.Stacktrace.Frames {
+				write(frame.Module)
+				write(frame.Function)
+				write(frame.AbsPath)
+				write(strconv.Itoa(frame.Lineno))
+			}
+		}
+	case event.Message != "" || event.LogEntry != nil:
+		write(event.Message)
+		if event.LogEntry != nil {
+			write(event.LogEntry.Message)
+			write(event.LogEntry.Formatted)
+		}
+	default:
+		return 0, false
+	}
+
+	return h.Sum64(), true
+}
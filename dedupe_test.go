@@ -0,0 +1,161 @@
+package sentry
+
+import (
+	"container/list"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestDedupeIntegration(now *time.Time) *dedupeIntegration {
+	return &dedupeIntegration{
+		window: time.Second,
+		now:    func() time.Time { return *now },
+		seen:   make(map[uint64]*list.Element),
+		order:  list.New(),
+	}
+}
+
+func messageEvent(message string) *Event {
+	event := NewEvent()
+	event.Message = message
+	return event
+}
+
+func TestDedupeIntegration(t *testing.T) {
+	now := time.Unix(0, 0)
+	di := newTestDedupeIntegration(&now)
+
+	if di.processor(messageEvent("foo"), nil) == nil {
+		t.Fatal("first event dropped")
+	}
+	if di.processor(messageEvent("foo"), nil) != nil {
+		t.Error("duplicate event not dropped")
+	}
+	if di.processor(messageEvent("foo"), nil) != nil {
+		t.Error("duplicate event not dropped")
+	}
+
+	event := di.processor(messageEvent("bar"), nil)
+	if event == nil {
+		t.Fatal("distinct event dropped")
+	}
+	assertEqual(t, event.Extra[dedupeExtraKey], 2)
+
+	event = di.processor(messageEvent("baz"), nil)
+	if _, ok := event.Extra[dedupeExtraKey]; ok {
+		t.Errorf("unexpected %s in %v", dedupeExtraKey, event.Extra)
+	}
+
+	now = now.Add(time.Second)
+	if di.processor(messageEvent("foo"), nil) == nil {
+		t.Error("event dropped after the window")
+	}
+}
+
+func TestDedupeIntegrationIgnores(t *testing.T) {
+	now := time.Unix(0, 0)
+	di := newTestDedupeIntegration(&now)
+
+	for i := 0; i < 2; i++ {
+		if di.processor(NewEvent(), nil) == nil {
+			t.Error("empty event dropped")
+		}
+		transaction := NewEvent()
+		transaction.Type = transactionType
+		transaction.Message = "foo"
+		if di.processor(transaction, nil) == nil {
+			t.Error("transaction dropped")
+		}
+	}
+}
+
+func TestDedupeIntegrationMaxEntries(t *testing.T) {
+	now := time.Unix(0, 0)
+	di := newTestDedupeIntegration(&now)
+
+	for i := 0; i <= maxDedupeEntries; i++ {
+		di.processor(messageEvent(strconv.Itoa(i)), nil)
+	}
+	assertEqual(t, di.order.Len(), maxDedupeEntries)
+	assertEqual(t, len(di.seen), maxDedupeEntries)
+
+	if di.processor(messageEvent("0"), nil) == nil {
+		t.Error("evicted event dropped")
+	}
+	if di.processor(messageEvent(strconv.Itoa(maxDedupeEntries)), nil) != nil {
+		t.Error("duplicate event not dropped")
+	}
+}
+
+func TestDedupeKey(t *testing.T) {
+	newEvent := func(line int) *Event {
+		event := NewEvent()
+		event.Exception = []Exception{{
+			Type:  "*errors.errorString",
+			Value: "boom",
+			Stacktrace: &Stacktrace{Frames: []Frame{
+				{Module: "main", Function: "main", AbsPath: "/app/main.go", Lineno: line},
+			}},
+		}}
+		return event
+	}
+
+	a, _ := dedupeKey(newEvent(10))
+	b, _ := dedupeKey(newEvent(10))
+	c, _ := dedupeKey(newEvent(11))
+	if a != b {
+		t.Error("same exceptions have different keys")
+	}
+	if a == c {
+		t.Error("exceptions raised at different lines have the same key")
+	}
+}
+
+func TestDedupeKeyScope(t *testing.T) {
+	newEvent := func(level Level, fingerprint ...string) *Event {
+		event := messageEvent("payment failed")
+		event.Level = level
+		event.Fingerprint = fingerprint
+		return event
+	}
+
+	a, _ := dedupeKey(newEvent(LevelError, "checkout"))
+	b, _ := dedupeKey(newEvent(LevelError, "refund"))
+	c, _ := dedupeKey(newEvent(LevelWarning, "checkout"))
+	if a == b {
+		t.Error("events with different fingerprints have the same key")
+	}
+	if a == c {
+		t.Error("events with different levels have the same key")
+	}
+}
+
+func TestDedupeWindowOption(t *testing.T) {
+	tests := []struct {
+		window time.Duration
+		want   int
+	}{
+		{0, 1},
+		{-1, 2},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.window.String(), func(t *testing.T) {
+			transport := &TransportMock{}
+			client, err := NewClient(ClientOptions{
+				Transport:    transport,
+				DedupeWindow: tt.window,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = errors.New("boom")
+			for i := 0; i < 2; i++ {
+				client.CaptureException(err, nil, nil)
+			}
+			assertEqual(t, len(transport.events), tt.want)
+		})
+	}
+}
@@ -143,6 +143,41 @@ func (dsn Dsn) String() string {
 	return url
 }
 
+// GetScheme returns the scheme of the DSN, either "http" or "https".
+func (dsn Dsn) GetScheme() string {
+	return string(dsn.scheme)
+}
+
+// GetPublicKey returns the public key of the DSN.
+func (dsn Dsn) GetPublicKey() string {
+	return dsn.publicKey
+}
+
+// GetSecretKey returns the secret key of the DSN, if any.
+func (dsn Dsn) GetSecretKey() string {
+	return dsn.secretKey
+}
+
+// GetHost returns the host name of the DSN.
+func (dsn Dsn) GetHost() string {
+	return dsn.host
+}
+
+// GetPort returns the port of the DSN.
+func (dsn Dsn) GetPort() int {
+	return dsn.port
+}
+
+// GetPath returns the path prefix of the DSN, if any.
+func (dsn Dsn) GetPath() string {
+	return dsn.path
+}
+
+// GetProjectID returns the project ID of the DSN.
+func (dsn Dsn) GetProjectID() int {
+	return dsn.projectID
+}
+
 // StoreAPIURL returns the URL of the store endpoint of the project associated
 // with the DSN.
 func (dsn Dsn) StoreAPIURL() *url.URL {
@@ -192,7 +227,9 @@ func (dsn Dsn) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON converts JSON data to the Dsn struct.
 func (dsn *Dsn) UnmarshalJSON(data []byte) error {
 	var str string
-	_ = json.Unmarshal(data, &str)
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
 	newDsn, err := NewDsn(str)
 	if err != nil {
 		return err
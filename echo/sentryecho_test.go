@@ -0,0 +1,56 @@
+package sentryecho_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	sentryecho "github.com/getsentry/sentry-go/echo"
+	"github.com/google/go-cmp/cmp"
+	"github.com/labstack/echo/v4"
+)
+
+func TestIntegrationRequestBody(t *testing.T) {
+	eventsCh := make(chan *sentry.Event, 1)
+	err := sentry.Init(sentry.ClientOptions{
+		BeforeSend: func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			eventsCh <- event
+			return event
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := echo.New()
+	router.Use(sentryecho.New(sentryecho.Options{}))
+	router.POST("/post", func(c echo.Context) error {
+		body, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			t.Error(err)
+		}
+		sentryecho.GetHubFromContext(c).CaptureMessage("post: " + string(body))
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/post", strings.NewReader(`{"name":"john"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if ok := sentry.Flush(time.Second); !ok {
+		t.Fatal("sentry.Flush timed out")
+	}
+	close(eventsCh)
+	event := <-eventsCh
+	if event == nil || event.Request == nil {
+		t.Fatalf("no event with request: %v", event)
+	}
+	want := map[string]interface{}{"name": "john"}
+	if diff := cmp.Diff(want, event.Request.Data); diff != "" {
+		t.Fatalf("Request data mismatch (-want +got):\n%s", diff)
+	}
+}
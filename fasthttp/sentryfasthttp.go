@@ -125,7 +125,9 @@ func convert(ctx *fasthttp.RequestCtx) *http.Request {
 	r.URL.RawQuery = string(ctx.URI().QueryString())
 
 	// Body
-	r.Body = ioutil.NopCloser(bytes.NewReader(ctx.Request.Body()))
+	body := ctx.Request.Body()
+	r.ContentLength = int64(len(body))
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
 
 	return r
 }
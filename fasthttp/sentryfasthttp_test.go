@@ -18,6 +18,7 @@ import (
 
 func TestIntegration(t *testing.T) {
 	largePayload := strings.Repeat("Large", 3*1024) // 15 KB
+	unhandled := false
 
 	tests := []struct {
 		Path    string
@@ -34,8 +35,14 @@ func TestIntegration(t *testing.T) {
 			},
 
 			WantEvent: &sentry.Event{
-				Level:   sentry.LevelFatal,
-				Message: "test",
+				Level: sentry.LevelFatal,
+				Exception: []sentry.Exception{
+					{
+						Type:      "panic",
+						Value:     "test",
+						Mechanism: &sentry.Mechanism{Type: sentry.MechanismTypePanic, Handled: &unhandled},
+					},
+				},
 				Request: &sentry.Request{
 					URL:    "http://example.com/panic",
 					Method: "GET",
@@ -109,7 +116,6 @@ func TestIntegration(t *testing.T) {
 					URL:    "http://example.com/post/large",
 					Method: "POST",
 					// Actual request body omitted because too large.
-					Data: "",
 					Headers: map[string]string{
 						"Content-Length": "15360",
 						"Content-Type":   "application/x-www-form-urlencoded",
@@ -210,12 +216,18 @@ func TestIntegration(t *testing.T) {
 	for e := range eventsCh {
 		got = append(got, e)
 	}
-	opt := cmpopts.IgnoreFields(
-		sentry.Event{},
-		"Contexts", "EventID", "Extra", "Platform",
-		"Sdk", "ServerName", "Tags", "Timestamp",
-	)
-	if diff := cmp.Diff(want, got, opt); diff != "" {
+	opts := cmp.Options{
+		cmpopts.IgnoreFields(
+			sentry.Event{},
+			"Contexts", "EventID", "Extra", "Platform",
+			"Sdk", "ServerName", "Tags", "Timestamp",
+		),
+		cmpopts.IgnoreFields(
+			sentry.Exception{},
+			"Stacktrace",
+		),
+	}
+	if diff := cmp.Diff(want, got, opts); diff != "" {
 		t.Fatalf("Events mismatch (-want +got):\n%s", diff)
 	}
 
@@ -0,0 +1,237 @@
+package sentry
+
+import (
+	"regexp"
+	"strings"
+)
+
+// A FingerprintRule sets the fingerprint of the events it matches, to control
+// how they are grouped into issues. See the FingerprintRules client option.
+//
+// Matchers are glob patterns, in which * matches any sequence of characters
+// and ? matches a single character. Empty matchers match any event. A rule
+// matches an event when all its matchers match.
+type FingerprintRule struct {
+	// Type matches the type of any exception of the event.
+	Type string
+	// Module matches the module of any stack trace frame of the event.
+	Module string
+	// Function matches the function of any stack trace frame of the event.
+	Function string
+	// Message matches the message of the event or the value of any of its
+	// exceptions.
+	Message string
+	// Tags match the values of the tags of the event, by tag name.
+	Tags map[string]string
+	// Level matches the level of the event.
+	Level string
+
+	// Fingerprint is the fingerprint of the matched events. Its elements may
+	// be placeholders, replaced with values of the event:
+	//
+	//  {{ default }}   the default grouping of Sentry
+	//  {{ type }}      the type of the most recent exception
+	//  {{ module }}    the module of the most recent in-app frame
+	//  {{ function }}  the function of the most recent in-app frame
+	//  {{ level }}     the level of the event
+	//  {{ tags.name }} the value of the tag name
+	Fingerprint []string
+}
+
+// fingerprintRule is a FingerprintRule with compiled matchers. Nil matchers
+// match any value.
+type fingerprintRule struct {
+	typ, module, function, message, level *regexp.Regexp
+	tags                                  map[string]*regexp.Regexp
+	fingerprint                           []string
+}
+
+func compileFingerprintRules(rules []FingerprintRule) []*fingerprintRule {
+	compiled := make([]*fingerprintRule, 0, len(rules))
+	for _, rule := range rules {
+		c := &fingerprintRule{
+			typ:         compileGlob(rule.Type),
+			module:      compileGlob(rule.Module),
+			function:    compileGlob(rule.Function),
+			message:     compileGlob(rule.Message),
+			level:       compileGlob(rule.Level),
+			fingerprint: rule.Fingerprint,
+		}
+		if len(rule.Tags) > 0 {
+			c.tags = make(map[string]*regexp.Regexp, len(rule.Tags))
+			for k, v := range rule.Tags {
+				c.tags[k] = compileGlob(v)
+			}
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled
+}
+
+// compileGlob compiles a glob pattern into an anchored regular expression, in
+// which wildcards match newlines too, as in the values of joined errors. It
+// returns nil for the empty pattern.
+func compileGlob(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// applyFingerprintRules sets the fingerprint of the event from the first rule
+// that matches it. Events that already have a fingerprint are left untouched.
+func applyFingerprintRules(event *Event, rules []*fingerprintRule) {
+	if len(event.Fingerprint) > 0 {
+		return
+	}
+	for _, rule := range rules {
+		if rule.match(event) {
+			event.Fingerprint = rule.expand(event)
+			return
+		}
+	}
+}
+
+func (rule *fingerprintRule) match(event *Event) bool {
+	if rule.level != nil && !rule.level.MatchString(string(event.Level)) {
+		return false
+	}
+	for k, re := range rule.tags {
+		v, ok := event.Tags[k]
+		if !ok || !re.MatchString(v) {
+			return false
+		}
+	}
+	if rule.typ != nil {
+		var found bool
+		for _, ex := range event.Exception {
+			if rule.typ.MatchString(ex.Type) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if rule.message != nil {
+		messages := []string{event.Message}
+		if event.LogEntry != nil {
+			messages = append(messages, event.LogEntry.Message, event.LogEntry.Formatted)
+		}
+		for _, ex := range event.Exception {
+			messages = append(messages, ex.Value)
+		}
+		if !matchAny(rule.message, messages) {
+			return false
+		}
+	}
+	if rule.module != nil || rule.function != nil {
+		var found bool
+		for _, frame := range eventFrames(event) {
+			if (rule.module == nil || rule.module.MatchString(frame.Module)) &&
+				(rule.function == nil || rule.function.MatchString(frame.Function)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(re *regexp.Regexp, values []string) bool {
+	for _, v := range values {
+		if v != "" && re.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// eventFrames returns the stack trace frames of the exceptions and threads of
+// the event, most recent exception first and most recent frame first.
+func eventFrames(event *Event) []Frame {
+	var stacktraces []*Stacktrace
+	for i := len(event.Exception) - 1; i >= 0; i-- {
+		stacktraces = append(stacktraces, event.Exception[i].Stacktrace)
+	}
+	for _, thread := range event.Threads {
+		stacktraces = append(stacktraces, thread.Stacktrace)
+	}
+	var frames []Frame
+	for _, stacktrace := range stacktraces {
+		if stacktrace == nil {
+			continue
+		}
+		for i := len(stacktrace.Frames) - 1; i >= 0; i-- {
+			frames = append(frames, stacktrace.Frames[i])
+		}
+	}
+	return frames
+}
+
+var placeholderRegexp = regexp.MustCompile(`^\{\{\s*(\S+)\s*\}\}$`)
+
+// expand returns the fingerprint of the rule with placeholders replaced with
+// values of the event. Unknown placeholders are kept as is.
+func (rule *fingerprintRule) expand(event *Event) []string {
+	fingerprint := make([]string, len(rule.fingerprint))
+	for i, s := range rule.fingerprint {
+		fingerprint[i] = s
+		m := placeholderRegexp.FindStringSubmatch(s)
+		if m == nil {
+			continue
+		}
+		switch name := m[1]; {
+		case name == "default":
+			// Sentry recognizes the placeholder as is.
+			fingerprint[i] = "{{ default }}"
+		case name == "type":
+			if len(event.Exception) > 0 {
+				fingerprint[i] = event.Exception[len(event.Exception)-1].Type
+			}
+		case name == "module" || name == "function":
+			frame := inAppFrame(eventFrames(event))
+			if name == "module" {
+				fingerprint[i] = frame.Module
+			} else {
+				fingerprint[i] = frame.Function
+			}
+		case name == "level":
+			fingerprint[i] = string(event.Level)
+		case strings.HasPrefix(name, "tags."):
+			fingerprint[i] = event.Tags[strings.TrimPrefix(name, "tags.")]
+		}
+	}
+	return fingerprint
+}
+
+// inAppFrame returns the first in-app frame, or the first frame if none is in
+// app.
+func inAppFrame(frames []Frame) Frame {
+	for _, frame := range frames {
+		if frame.InApp {
+			return frame
+		}
+	}
+	if len(frames) > 0 {
+		return frames[0]
+	}
+	return Frame{}
+}
@@ -0,0 +1,102 @@
+package sentry
+
+import (
+	"testing"
+)
+
+func TestApplyFingerprintRules(t *testing.T) {
+	newEvent := func() *Event {
+		event := NewEvent()
+		event.Level = LevelError
+		event.Tags = map[string]string{"service": "billing"}
+		event.Exception = []Exception{{
+			Type:  "*net.OpError",
+			Value: "dial tcp 10.0.0.1:5432: connection refused",
+			Stacktrace: &Stacktrace{Frames: []Frame{
+				{Module: "example.com/app", Function: "main", InApp: true},
+				{Module: "example.com/app/db", Function: "(*Pool).Get", InApp: true},
+				{Module: "net", Function: "Dial"},
+			}},
+		}}
+		return event
+	}
+
+	tests := []struct {
+		name string
+		rule FingerprintRule
+		// value replaces the exception value, if set.
+		value string
+		want  []string
+	}{
+		{
+			name: "Type",
+			rule: FingerprintRule{Type: "*net.OpError", Fingerprint: []string{"network"}},
+			want: []string{"network"},
+		},
+		{
+			name: "Message",
+			rule: FingerprintRule{Message: "dial tcp *: connection refused", Fingerprint: []string{"{{ default }}", "refused"}},
+			want: []string{"{{ default }}", "refused"},
+		},
+		{
+			name: "ModuleAndFunction",
+			rule: FingerprintRule{Module: "example.com/app/*", Function: "(*Pool).*", Fingerprint: []string{"{{module}}", "{{ function }}"}},
+			want: []string{"example.com/app/db", "(*Pool).Get"},
+		},
+		{
+			name: "TagsAndLevel",
+			rule: FingerprintRule{Tags: map[string]string{"service": "bill*"}, Level: "error", Fingerprint: []string{"{{ tags.service }}", "{{ level }}", "{{ type }}", "{{ unknown }}"}},
+			want: []string{"billing", "error", "*net.OpError", "{{ unknown }}"},
+		},
+		{
+			name:  "MultilineValue",
+			rule:  FingerprintRule{Message: "*db timeout*", Fingerprint: []string{"timeout"}},
+			value: "query failed\ndb timeout\nretrying",
+			want:  []string{"timeout"},
+		},
+		{
+			name: "NoMatch",
+			rule: FingerprintRule{Function: "Dial?", Fingerprint: []string{"dial"}},
+		},
+		{
+			name: "MissingTag",
+			rule: FingerprintRule{Tags: map[string]string{"region": "*"}, Fingerprint: []string{"region"}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			event := newEvent()
+			if tt.value != "" {
+				event.Exception[0].Value = tt.value
+			}
+			applyFingerprintRules(event, compileFingerprintRules([]FingerprintRule{tt.rule}))
+			assertEqual(t, event.Fingerprint, tt.want)
+		})
+	}
+}
+
+func TestFingerprintRulesOption(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{
+		Transport: transport,
+		FingerprintRules: []FingerprintRule{
+			{Message: "user * not found", Fingerprint: []string{"user-not-found"}},
+			{Message: "*", Fingerprint: []string{"catch-all"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.CaptureMessage("user 42 not found", nil, nil)
+	assertEqual(t, transport.lastEvent.Fingerprint, []string{"user-not-found"})
+
+	client.CaptureMessage("something else", nil, nil)
+	assertEqual(t, transport.lastEvent.Fingerprint, []string{"catch-all"})
+
+	scope := NewScope()
+	scope.SetFingerprint([]string{"explicit"})
+	client.CaptureMessage("user 42 not found", nil, scope)
+	assertEqual(t, transport.lastEvent.Fingerprint, []string{"explicit"})
+}
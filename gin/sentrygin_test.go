@@ -0,0 +1,56 @@
+package sentrygin_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	sentrygin "github.com/getsentry/sentry-go/gin"
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIntegrationRequestBody(t *testing.T) {
+	eventsCh := make(chan *sentry.Event, 1)
+	err := sentry.Init(sentry.ClientOptions{
+		BeforeSend: func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			eventsCh <- event
+			return event
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(sentrygin.New(sentrygin.Options{}))
+	router.POST("/post", func(c *gin.Context) {
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		sentrygin.GetHubFromContext(c).CaptureMessage("post: " + string(body))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/post", strings.NewReader(`{"name":"john"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if ok := sentry.Flush(time.Second); !ok {
+		t.Fatal("sentry.Flush timed out")
+	}
+	close(eventsCh)
+	event := <-eventsCh
+	if event == nil || event.Request == nil {
+		t.Fatalf("no event with request: %v", event)
+	}
+	want := map[string]interface{}{"name": "john"}
+	if diff := cmp.Diff(want, event.Request.Data); diff != "" {
+		t.Fatalf("Request data mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,169 @@
+package sentry
+
+import (
+	"bufio"
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Limits on the goroutines attached to events when the AttachGoroutines
+// option is enabled. They protect the SDK from programs with a huge number of
+// goroutines.
+const (
+	maxGoroutineDumpBytes = 8 * 1024 * 1024
+	maxGoroutineThreads   = 200
+)
+
+// goroutineThreads returns the stack traces of all goroutines as threads,
+// keeping the frames for which keep returns true. The calling goroutine comes
+// first and is marked as current and crashed.
+func goroutineThreads(keep func(Frame) bool) []Thread {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxGoroutineDumpBytes {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	threads := parseGoroutines(buf, keep)
+	if len(threads) > maxGoroutineThreads {
+		threads = threads[:maxGoroutineThreads]
+	}
+	if len(threads) > 0 {
+		threads[0].Crashed = true
+		threads[0].Current = true
+		if st := threads[0].Stacktrace; st != nil {
+			if st.Frames = dropCaptureFrames(st.Frames); len(st.Frames) == 0 {
+				threads[0].Stacktrace = nil
+			}
+		}
+	}
+	return threads
+}
+
+// parseGoroutines parses the output of runtime.Stack into threads, one for
+// each goroutine. Goroutines are separated by blank lines and look like this:
+//
+//	goroutine 7 [chan receive, 2 minutes]:
+//	main.worker(0xc000010000)
+//		/app/main.go:42 +0x65
+//	created by main.main in goroutine 1
+//		/app/main.go:17 +0x1d
+//
+// The thread name is the goroutine header, including the wait duration, and
+// the thread state is the reason the goroutine is waiting. The frame of the
+// function that started the goroutine is included as the outermost frame.
+// Only the frames for which keep returns true are kept.
+func parseGoroutines(b []byte, keep func(Frame) bool) []Thread {
+	var threads []Thread
+	var thread *Thread
+	// frames holds the frames of the current goroutine, innermost first.
+	var frames []runtime.Frame
+	// function is the function of the frame whose location is expected in the
+	// next line.
+	var function string
+
+	flush := func() {
+		if thread == nil {
+			return
+		}
+		stacktrace := make([]Frame, 0, len(frames))
+		for i := len(frames) - 1; i >= 0; i-- {
+			stacktrace = append(stacktrace, NewFrame(frames[i]))
+		}
+		if stacktrace = selectFrames(stacktrace, keep); len(stacktrace) > 0 {
+			thread.Stacktrace = &Stacktrace{Frames: stacktrace}
+		}
+		threads = append(threads, *thread)
+		thread, frames, function = nil, nil, ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxGoroutineDumpBytes)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "goroutine "):
+			flush()
+			thread = parseGoroutineHeader(line)
+		case thread == nil:
+			// Not part of a goroutine, ignore.
+		case strings.HasPrefix(line, "\t"):
+			if function == "" {
+				continue
+			}
+			file, lineno := parseGoroutineLocation(line)
+			frames = append(frames, runtime.Frame{
+				Function: function,
+				File:     file,
+				Line:     lineno,
+			})
+			function = ""
+		case strings.HasPrefix(line, "created by "):
+			function = strings.TrimPrefix(line, "created by ")
+			// Since Go 1.21, the ID of the parent goroutine follows the
+			// function name.
+			if i := strings.Index(function, " in goroutine "); i >= 0 {
+				function = function[:i]
+			}
+		case strings.HasPrefix(line, "..."):
+			// "...additional frames elided..."
+			function = ""
+		default:
+			// A function call, followed by its arguments.
+			function = line
+			if i := strings.LastIndex(line, "("); i > 0 {
+				function = line[:i]
+			}
+			// Calls to the panic builtin are reported without a package.
+			if function == "panic" {
+				function = "runtime.gopanic"
+			}
+		}
+	}
+	flush()
+
+	return threads
+}
+
+// parseGoroutineHeader parses a goroutine header, such as
+// "goroutine 7 [chan receive, 2 minutes]:", into a thread.
+func parseGoroutineHeader(line string) *Thread {
+	header := strings.TrimSuffix(line, ":")
+	thread := &Thread{Name: header}
+	fields := strings.SplitN(strings.TrimPrefix(header, "goroutine "), " ", 2)
+	thread.ID = fields[0]
+	if len(fields) == 2 {
+		state := strings.TrimSuffix(strings.TrimPrefix(fields[1], "["), "]")
+		// The state may be followed by the wait duration and other details.
+		if i := strings.Index(state, ","); i >= 0 {
+			state = state[:i]
+		}
+		thread.State = state
+	}
+	return thread
+}
+
+// parseGoroutineLocation parses the file and line of a frame, such as
+// "\t/app/main.go:42 +0x65".
+func parseGoroutineLocation(line string) (file string, lineno int) {
+	location := strings.TrimSpace(line)
+	if i := strings.LastIndex(location, " +0x"); i >= 0 {
+		location = location[:i]
+	}
+	i := strings.LastIndex(location, ":")
+	if i < 0 {
+		return location, 0
+	}
+	lineno, err := strconv.Atoi(location[i+1:])
+	if err != nil {
+		return location, 0
+	}
+	return location[:i], lineno
+}
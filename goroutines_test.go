@@ -0,0 +1,132 @@
+package sentry
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const goroutineDump = `goroutine 1 [running]:
+main.main.func1()
+	/app/main.go:12 +0x25
+panic({0x4a2b60?, 0x4ef0f8?})
+	/usr/local/go/src/runtime/panic.go:914 +0x21f
+main.(*Server).handle(0xc000012345, {0x4ef0f8, 0x1})
+	/app/server.go:42 +0x65
+main.main()
+	/app/main.go:20 +0x1d
+
+goroutine 7 [chan receive, 2 minutes]:
+main.worker(...)
+	/app/worker.go:8
+created by main.main in goroutine 1
+	/app/main.go:17 +0x1d
+
+goroutine 8 [select, locked to thread]:
+...additional frames elided...
+created by main.startPool
+	/app/pool.go:30 +0x42
+
+goroutine 9 [running]:
+	goroutine running on other thread; stack unavailable
+`
+
+func TestParseGoroutines(t *testing.T) {
+	got := parseGoroutines([]byte(goroutineDump), DefaultFrameFilter)
+	want := []Thread{
+		{
+			ID:    "1",
+			Name:  "goroutine 1 [running]",
+			State: "running",
+			Stacktrace: &Stacktrace{Frames: []Frame{
+				{Function: "main", Symbol: "main.main", Module: "main", AbsPath: "/app/main.go", Lineno: 20, InApp: true},
+				{Function: "(*Server).handle", Symbol: "main.(*Server).handle", Module: "main", AbsPath: "/app/server.go", Lineno: 42, InApp: true},
+				{Function: "main.func1", Symbol: "main.main.func1", Module: "main", AbsPath: "/app/main.go", Lineno: 12, InApp: true},
+			}},
+		},
+		{
+			ID:    "7",
+			Name:  "goroutine 7 [chan receive, 2 minutes]",
+			State: "chan receive",
+			Stacktrace: &Stacktrace{Frames: []Frame{
+				{Function: "main", Symbol: "main.main", Module: "main", AbsPath: "/app/main.go", Lineno: 17, InApp: true},
+				{Function: "worker", Symbol: "main.worker", Module: "main", AbsPath: "/app/worker.go", Lineno: 8, InApp: true},
+			}},
+		},
+		{
+			ID:    "8",
+			Name:  "goroutine 8 [select, locked to thread]",
+			State: "select",
+			Stacktrace: &Stacktrace{Frames: []Frame{
+				{Function: "startPool", Symbol: "main.startPool", Module: "main", AbsPath: "/app/pool.go", Lineno: 30, InApp: true},
+			}},
+		},
+		{
+			ID:    "9",
+			Name:  "goroutine 9 [running]",
+			State: "running",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Threads mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRecoverAttachGoroutines(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{
+		Transport:        transport,
+		AttachGoroutines: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	defer wg.Done()
+	started := make(chan struct{})
+	go func() {
+		close(started)
+		wg.Wait()
+	}()
+	<-started
+
+	func() {
+		defer client.Recover(nil, nil, nil)
+		panic(errors.New("panic error"))
+	}()
+
+	event := transport.lastEvent
+	if event == nil {
+		t.Fatal("missing event")
+	}
+	if len(event.Threads) < 2 {
+		t.Fatalf("got %d threads, want at least 2", len(event.Threads))
+	}
+	current := event.Threads[0]
+	if !current.Crashed || !current.Current {
+		t.Errorf("first thread = %+v, want crashed and current", current)
+	}
+	assertEqual(t, event.Exception[len(event.Exception)-1].ThreadID, current.ID)
+
+	var found bool
+	for _, thread := range event.Threads[1:] {
+		if thread.Crashed || thread.Current {
+			t.Errorf("thread %s marked as crashed or current", thread.ID)
+		}
+		if thread.Stacktrace == nil {
+			continue
+		}
+		for _, frame := range thread.Stacktrace.Frames {
+			if frame.Module == "sync" && frame.Function == "(*WaitGroup).Wait" {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Errorf("blocked goroutine not found in %+v", event.Threads)
+	}
+}
@@ -17,6 +17,7 @@ import (
 
 func TestIntegration(t *testing.T) {
 	largePayload := strings.Repeat("Large", 3*1024) // 15 KB
+	unhandled := false
 
 	tests := []struct {
 		Path    string
@@ -33,8 +34,14 @@ func TestIntegration(t *testing.T) {
 			}),
 
 			WantEvent: &sentry.Event{
-				Level:   sentry.LevelFatal,
-				Message: "test",
+				Level: sentry.LevelFatal,
+				Exception: []sentry.Exception{
+					{
+						Type:      "panic",
+						Value:     "test",
+						Mechanism: &sentry.Mechanism{Type: sentry.MechanismTypePanic, Handled: &unhandled},
+					},
+				},
 				Request: &sentry.Request{
 					URL:    "/panic",
 					Method: "GET",
@@ -113,7 +120,6 @@ func TestIntegration(t *testing.T) {
 					URL:    "/post/large",
 					Method: "POST",
 					// Actual request body omitted because too large.
-					Data: "",
 					Headers: map[string]string{
 						"Accept-Encoding": "gzip",
 						"Content-Length":  "15360",
@@ -138,7 +144,6 @@ func TestIntegration(t *testing.T) {
 					URL:    "/post/body-ignored",
 					Method: "POST",
 					// Actual request body omitted because not read.
-					Data: "",
 					Headers: map[string]string{
 						"Accept-Encoding": "gzip",
 						"Content-Length":  "46",
@@ -215,6 +220,10 @@ func TestIntegration(t *testing.T) {
 			sentry.Request{},
 			"Env",
 		),
+		cmpopts.IgnoreFields(
+			sentry.Exception{},
+			"Stacktrace",
+		),
 	}
 	if diff := cmp.Diff(want, got, opts); diff != "" {
 		t.Fatalf("Events mismatch (-want +got):\n%s", diff)
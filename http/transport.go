@@ -0,0 +1,55 @@
+package sentryhttp
+
+import (
+	"net/http"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Transport is an http.RoundTripper that records a breadcrumb for every
+// request sent through it. Breadcrumbs are added to the hub of the request
+// context, if any, or else to the current hub.
+//
+// Use it in HTTP clients to have the outgoing requests made while handling a
+// request reported along with errors:
+//
+//	client := &http.Client{Transport: &sentryhttp.Transport{}}
+type Transport struct {
+	// Base is the RoundTripper used to send requests. If nil,
+	// http.DefaultTransport is used.
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	res, err := base.RoundTrip(req)
+
+	hub := sentry.GetHubFromContext(req.Context())
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	// The query string and credentials are left out of the URL, as they
+	// commonly hold sensitive data.
+	u := *req.URL
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+
+	var breadcrumb *sentry.Breadcrumb
+	if err != nil {
+		breadcrumb = sentry.NewHTTPBreadcrumb(req.Method, u.String(), 0, err.Error())
+		breadcrumb.Level = sentry.LevelError
+	} else {
+		breadcrumb = sentry.NewHTTPBreadcrumb(req.Method, u.String(), res.StatusCode, http.StatusText(res.StatusCode))
+	}
+	hub.AddBreadcrumb(breadcrumb, &sentry.BreadcrumbHint{
+		"request":  req,
+		"response": res,
+	})
+
+	return res, err
+}
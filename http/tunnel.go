@@ -0,0 +1,195 @@
+package sentryhttp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// defaultMaxEnvelopeSize is the default maximum size of envelopes accepted by
+// a Tunnel.
+const defaultMaxEnvelopeSize = 20 * 1024 * 1024
+
+// defaultTunnelTimeout is the default timeout of requests forwarded by a
+// Tunnel.
+const defaultTunnelTimeout = 30 * time.Second
+
+// A Tunnel is an HTTP handler that forwards envelopes sent by browser SDKs to
+// Sentry. Serving it from the same origin as a web application allows the
+// frontend to report errors even when ad blockers prevent it from reaching
+// Sentry directly.
+//
+// The browser SDKs must be configured to use the URL the Tunnel is served at
+// as their tunnel option.
+type Tunnel struct {
+	allowed         []*sentry.Dsn
+	maxEnvelopeSize int64
+	timeout         time.Duration
+
+	clientOnce sync.Once
+	client     *http.Client
+}
+
+// TunnelOptions configure a Tunnel.
+type TunnelOptions struct {
+	// AllowedDSNs is the list of DSNs envelopes may be forwarded to. Envelopes
+	// for any other DSN are rejected. At least one DSN is required.
+	AllowedDSNs []string
+	// MaxEnvelopeSize is the maximum size in bytes of an envelope. Larger
+	// envelopes are rejected. Defaults to 20 MB.
+	MaxEnvelopeSize int64
+	// Timeout for the forwarded requests. Defaults to 30 seconds.
+	Timeout time.Duration
+	// HTTPClient is used to forward envelopes to Sentry. If nil, a client is
+	// created with sentry.NewHTTPClient from the options of the client bound
+	// to the current hub when the first envelope is forwarded, such that a
+	// Tunnel may be created before calling sentry.Init.
+	HTTPClient *http.Client
+}
+
+// NewTunnel returns a new Tunnel. It returns an error if any of the allowed
+// DSNs is invalid.
+func NewTunnel(options TunnelOptions) (*Tunnel, error) {
+	if len(options.AllowedDSNs) == 0 {
+		return nil, errors.New("sentryhttp: tunnel requires at least one allowed DSN")
+	}
+	t := &Tunnel{
+		maxEnvelopeSize: options.MaxEnvelopeSize,
+		timeout:         options.Timeout,
+		client:          options.HTTPClient,
+	}
+	for _, s := range options.AllowedDSNs {
+		dsn, err := sentry.NewDsn(s)
+		if err != nil {
+			return nil, err
+		}
+		t.allowed = append(t.allowed, dsn)
+	}
+	if t.maxEnvelopeSize == 0 {
+		t.maxEnvelopeSize = defaultMaxEnvelopeSize
+	}
+	if t.timeout == 0 {
+		t.timeout = defaultTunnelTimeout
+	}
+	return t, nil
+}
+
+// httpClient returns the HTTP client forwarding envelopes, creating it from
+// the options of the client bound to the current hub on first use unless one
+// was given in the TunnelOptions.
+func (t *Tunnel) httpClient() *http.Client {
+	t.clientOnce.Do(func() {
+		if t.client != nil {
+			return
+		}
+		var clientOptions sentry.ClientOptions
+		if client := sentry.CurrentHub().Client(); client != nil {
+			clientOptions = client.Options()
+		}
+		t.client = sentry.NewHTTPClient(clientOptions, t.timeout)
+	})
+	return t.client
+}
+
+// ServeHTTP implements http.Handler.
+func (t *Tunnel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, t.maxEnvelopeSize+1))
+	if err != nil {
+		http.Error(w, "cannot read envelope", http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > t.maxEnvelopeSize {
+		http.Error(w, "envelope too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	dsn, err := envelopeDSN(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	allowed := t.match(dsn)
+	if allowed == nil {
+		sentry.Logger.Printf("Tunnel rejected envelope for DSN not in the allowlist: %s", dsn)
+		http.Error(w, "DSN not allowed", http.StatusForbidden)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, allowed.EnvelopeAPIURL().String(), bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	req = req.WithContext(r.Context())
+	for k, v := range allowed.RequestHeaders() {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+
+	res, err := t.httpClient().Do(req)
+	if err != nil {
+		sentry.Logger.Printf("Tunnel failed to forward envelope: %v", err)
+		http.Error(w, "cannot forward envelope", http.StatusBadGateway)
+		return
+	}
+	defer res.Body.Close()
+
+	for _, k := range []string{"Content-Type", "Retry-After", "X-Sentry-Rate-Limits"} {
+		if v := res.Header.Get(k); v != "" {
+			w.Header().Set(k, v)
+		}
+	}
+	w.WriteHeader(res.StatusCode)
+	_, _ = io.Copy(w, res.Body)
+}
+
+// match returns the allowed DSN that matches dsn, or nil if there is none.
+// DSNs match if they identify the same project on the same host with the same
+// public key.
+func (t *Tunnel) match(dsn *sentry.Dsn) *sentry.Dsn {
+	for _, allowed := range t.allowed {
+		if allowed.GetScheme() == dsn.GetScheme() &&
+			allowed.GetHost() == dsn.GetHost() &&
+			allowed.GetPort() == dsn.GetPort() &&
+			allowed.GetPath() == dsn.GetPath() &&
+			allowed.GetProjectID() == dsn.GetProjectID() &&
+			allowed.GetPublicKey() == dsn.GetPublicKey() {
+			return allowed
+		}
+	}
+	return nil
+}
+
+// envelopeDSN parses the DSN from the header of an envelope, that is, its
+// first line.
+func envelopeDSN(envelope []byte) (*sentry.Dsn, error) {
+	line, err := bufio.NewReader(bytes.NewReader(envelope)).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	var header struct {
+		Dsn string `json:"dsn"`
+	}
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, fmt.Errorf("invalid envelope header: %v", err)
+	}
+	if header.Dsn == "" {
+		return nil, errors.New("envelope header has no DSN")
+	}
+	return sentry.NewDsn(header.Dsn)
+}
@@ -0,0 +1,141 @@
+package sentryhttp_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	sentryhttp "github.com/getsentry/sentry-go/http"
+	"github.com/getsentry/sentry-go/sentrytest"
+)
+
+func envelope(dsn, message string) string {
+	payload := fmt.Sprintf(`{"event_id":"9ec79c33ec9942ab8353589fcb2e04dc","message":%q}`, message)
+	return fmt.Sprintf(`{"event_id":"9ec79c33ec9942ab8353589fcb2e04dc","dsn":%q}`+"\n"+
+		`{"type":"event","length":%d}`+"\n%s\n", dsn, len(payload), payload)
+}
+
+func TestTunnel(t *testing.T) {
+	srv := sentrytest.NewServer()
+	defer srv.Close()
+
+	tunnel, err := sentryhttp.NewTunnel(sentryhttp.TunnelOptions{
+		AllowedDSNs:     []string{srv.DSN()},
+		MaxEnvelopeSize: 1024,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{"Forwarded", http.MethodPost, envelope(srv.DSN(), "from browser"), http.StatusOK},
+		{"MethodNotAllowed", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"NoHeader", http.MethodPost, "", http.StatusBadRequest},
+		{"NoDSN", http.MethodPost, "{}\n", http.StatusBadRequest},
+		{"OtherProject", http.MethodPost, envelope(strings.Replace(srv.DSN(), "/1", "/2", 1), "x"), http.StatusForbidden},
+		{"OtherKey", http.MethodPost, envelope(strings.Replace(srv.DSN(), "public@", "other@", 1), "x"), http.StatusForbidden},
+		{"TooLarge", http.MethodPost, envelope(srv.DSN(), strings.Repeat("x", 1024)), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/tunnel", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			tunnel.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+
+	events := srv.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if events[0].Message != "from browser" {
+		t.Errorf("Message = %q, want %q", events[0].Message, "from browser")
+	}
+}
+
+func TestTunnelForwardsRateLimits(t *testing.T) {
+	srv := sentrytest.NewServer()
+	defer srv.Close()
+	srv.Enqueue(sentrytest.Response{StatusCode: http.StatusTooManyRequests, RetryAfter: "42"})
+
+	tunnel, err := sentryhttp.NewTunnel(sentryhttp.TunnelOptions{
+		AllowedDSNs: []string{srv.DSN()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/tunnel", strings.NewReader(envelope(srv.DSN(), "x")))
+	rec := httptest.NewRecorder()
+	tunnel.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "42" {
+		t.Errorf("Retry-After = %q, want %q", got, "42")
+	}
+}
+
+func TestTunnelCreatedBeforeInit(t *testing.T) {
+	srv := sentrytest.NewServer()
+	defer srv.Close()
+
+	hub := sentry.CurrentHub()
+	defer hub.BindClient(hub.Client())
+	hub.BindClient(nil)
+
+	tunnel, err := sentryhttp.NewTunnel(sentryhttp.TunnelOptions{
+		AllowedDSNs: []string{srv.DSN()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The transport options of the client bound after the tunnel was created
+	// apply to the forwarded envelopes.
+	var forwarded int32
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn: srv.DSN(),
+		HTTPTransport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&forwarded, 1)
+			return http.DefaultTransport.RoundTrip(r)
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub.BindClient(client)
+
+	req := httptest.NewRequest(http.MethodPost, "/tunnel", strings.NewReader(envelope(srv.DSN(), "x")))
+	rec := httptest.NewRecorder()
+	tunnel.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if n := atomic.LoadInt32(&forwarded); n != 1 {
+		t.Errorf("envelopes forwarded through the client transport = %d, want 1", n)
+	}
+}
+
+func TestNewTunnelInvalidOptions(t *testing.T) {
+	if _, err := sentryhttp.NewTunnel(sentryhttp.TunnelOptions{}); err == nil {
+		t.Error("got nil error without allowed DSNs")
+	}
+	if _, err := sentryhttp.NewTunnel(sentryhttp.TunnelOptions{AllowedDSNs: []string{"invalid"}}); err == nil {
+		t.Error("got nil error with invalid DSN")
+	}
+}
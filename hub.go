@@ -70,6 +70,9 @@ type stack []*layer
 
 // NewHub returns an instance of a Hub with provided Client and Scope bound.
 func NewHub(client *Client, scope *Scope) *Hub {
+	if client != nil && scope != nil {
+		scope.setRequestBodySize(client.Options().MaxRequestBodySize)
+	}
 	hub := Hub{
 		stack: &stack{{
 			client: client,
@@ -172,6 +175,9 @@ func (hub *Hub) PopScope() {
 func (hub *Hub) BindClient(client *Client) {
 	top := hub.stackTop()
 	top.SetClient(client)
+	if client != nil {
+		top.scope.setRequestBodySize(client.Options().MaxRequestBodySize)
+	}
 }
 
 // WithScope runs f in an isolated temporary scope.
@@ -242,6 +248,27 @@ func (hub *Hub) CaptureMessage(message string) *EventID {
 	return eventID
 }
 
+// CaptureMessagef calls CaptureMessageTemplate on currently bound Client
+// instance passing it a top-level Scope. The format string is used as the
+// message template and args as its parameters.
+// Returns EventID if successfully, or nil if there's no Scope or Client available.
+func (hub *Hub) CaptureMessagef(format string, args ...interface{}) *EventID {
+	client, scope := hub.Client(), hub.Scope()
+	if client == nil || scope == nil {
+		return nil
+	}
+	eventID := client.CaptureMessageTemplate(format, args, nil, scope)
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if eventID != nil {
+		hub.lastEventID = *eventID
+	} else {
+		hub.lastEventID = ""
+	}
+	return eventID
+}
+
 // CaptureException calls the method of a same name on currently bound Client instance
 // passing it a top-level Scope.
 // Returns EventID if successfully, or nil if there's no Scope or Client available.
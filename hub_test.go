@@ -11,7 +11,11 @@ import (
 )
 
 func setupHubTest() (*Hub, *Client, *Scope) {
-	client, _ := NewClient(ClientOptions{Dsn: "http://whatever@really.com/1337"})
+	client, _ := NewClient(ClientOptions{
+		Dsn: "http://whatever@really.com/1337",
+		// Tests capture the same events several times.
+		DedupeWindow: -1,
+	})
 	scope := NewScope()
 	hub := NewHub(client, scope)
 	return hub, client, scope
@@ -183,6 +187,9 @@ func TestLastEventIDUpdatesAfterCaptures(t *testing.T) {
 	messageID := hub.CaptureMessage("wat")
 	assertEqual(t, *messageID, hub.LastEventID())
 
+	messagefID := hub.CaptureMessagef("wat %d", 42)
+	assertEqual(t, *messagefID, hub.LastEventID())
+
 	errorID := hub.CaptureException(fmt.Errorf("wat"))
 	assertEqual(t, *errorID, hub.LastEventID())
 
@@ -7,6 +7,7 @@ import (
 	"runtime/debug"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // ================================
@@ -70,52 +71,99 @@ func (ei *environmentIntegration) SetupOnce(client *Client) {
 }
 
 func (ei *environmentIntegration) processor(event *Event, hint *EventHint) *Event {
-	// Initialize maps as necessary.
 	if event.Contexts == nil {
 		event.Contexts = make(map[string]interface{})
 	}
-	for _, name := range []string{"device", "os", "runtime"} {
-		if event.Contexts[name] == nil {
-			event.Contexts[name] = make(map[string]interface{})
+
+	// Set contextual information preserving existing data. Contexts of the
+	// typed context structs and maps are completed with the missing fields.
+	// Contexts of any other type are left untouched.
+	deviceContext := DeviceContext{
+		Arch:   runtime.GOARCH,
+		NumCPU: runtime.NumCPU(),
+	}
+	switch c := event.Contexts[DeviceContextKey].(type) {
+	case nil:
+		event.Contexts[DeviceContextKey] = deviceContext
+	case DeviceContext:
+		event.Contexts[DeviceContextKey] = c.merge(deviceContext)
+	case *DeviceContext:
+		if c == nil {
+			event.Contexts[DeviceContextKey] = deviceContext
+		} else {
+			event.Contexts[DeviceContextKey] = c.merge(deviceContext)
 		}
+	case map[string]interface{}:
+		event.Contexts[DeviceContextKey] = mergeContextMap(c, map[string]interface{}{
+			"arch":    deviceContext.Arch,
+			"num_cpu": deviceContext.NumCPU,
+		})
 	}
 
-	// Set contextual information preserving existing data. For each context, if
-	// the existing value is not of type map[string]interface{}, then no
-	// additional information is added.
-	if deviceContext, ok := event.Contexts["device"].(map[string]interface{}); ok {
-		if _, ok := deviceContext["arch"]; !ok {
-			deviceContext["arch"] = runtime.GOARCH
-		}
-		if _, ok := deviceContext["num_cpu"]; !ok {
-			deviceContext["num_cpu"] = runtime.NumCPU()
-		}
+	osContext := OSContext{
+		Name: runtime.GOOS,
 	}
-	if osContext, ok := event.Contexts["os"].(map[string]interface{}); ok {
-		if _, ok := osContext["name"]; !ok {
-			osContext["name"] = runtime.GOOS
+	switch c := event.Contexts[OSContextKey].(type) {
+	case nil:
+		event.Contexts[OSContextKey] = osContext
+	case OSContext:
+		event.Contexts[OSContextKey] = c.merge(osContext)
+	case *OSContext:
+		if c == nil {
+			event.Contexts[OSContextKey] = osContext
+		} else {
+			event.Contexts[OSContextKey] = c.merge(osContext)
 		}
+	case map[string]interface{}:
+		event.Contexts[OSContextKey] = mergeContextMap(c, map[string]interface{}{
+			"name": osContext.Name,
+		})
 	}
-	if runtimeContext, ok := event.Contexts["runtime"].(map[string]interface{}); ok {
-		if _, ok := runtimeContext["name"]; !ok {
-			runtimeContext["name"] = "go"
-		}
-		if _, ok := runtimeContext["version"]; !ok {
-			runtimeContext["version"] = runtime.Version()
-		}
-		if _, ok := runtimeContext["go_numroutines"]; !ok {
-			runtimeContext["go_numroutines"] = runtime.NumGoroutine()
-		}
-		if _, ok := runtimeContext["go_maxprocs"]; !ok {
-			runtimeContext["go_maxprocs"] = runtime.GOMAXPROCS(0)
-		}
-		if _, ok := runtimeContext["go_numcgocalls"]; !ok {
-			runtimeContext["go_numcgocalls"] = runtime.NumCgoCall()
+
+	runtimeContext := RuntimeContext{
+		Name:          "go",
+		Version:       runtime.Version(),
+		GoNumRoutines: runtime.NumGoroutine(),
+		GoMaxProcs:    runtime.GOMAXPROCS(0),
+		GoNumCgoCalls: runtime.NumCgoCall(),
+	}
+	switch c := event.Contexts[RuntimeContextKey].(type) {
+	case nil:
+		event.Contexts[RuntimeContextKey] = runtimeContext
+	case RuntimeContext:
+		event.Contexts[RuntimeContextKey] = c.merge(runtimeContext)
+	case *RuntimeContext:
+		if c == nil {
+			event.Contexts[RuntimeContextKey] = runtimeContext
+		} else {
+			event.Contexts[RuntimeContextKey] = c.merge(runtimeContext)
 		}
+	case map[string]interface{}:
+		event.Contexts[RuntimeContextKey] = mergeContextMap(c, map[string]interface{}{
+			"name":           runtimeContext.Name,
+			"version":        runtimeContext.Version,
+			"go_numroutines": runtimeContext.GoNumRoutines,
+			"go_maxprocs":    runtimeContext.GoMaxProcs,
+			"go_numcgocalls": runtimeContext.GoNumCgoCalls,
+		})
 	}
+
 	return event
 }
 
+// mergeContextMap returns a copy of context with the values in defaults added
+// for the keys context doesn't have.
+func mergeContextMap(context, defaults map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(context)+len(defaults))
+	for k, v := range defaults {
+		m[k] = v
+	}
+	for k, v := range context {
+		m[k] = v
+	}
+	return m
+}
+
 // ================================
 // Ignore Errors Integration
 // ================================
@@ -169,6 +217,15 @@ func getIgnoreErrorsSuspects(event *Event) []string {
 		suspects = append(suspects, event.Message)
 	}
 
+	if event.LogEntry != nil {
+		if event.LogEntry.Message != "" {
+			suspects = append(suspects, event.LogEntry.Message)
+		}
+		if event.LogEntry.Formatted != "" && event.LogEntry.Formatted != event.Message {
+			suspects = append(suspects, event.LogEntry.Formatted)
+		}
+	}
+
 	for _, ex := range event.Exception {
 		suspects = append(suspects, ex.Type)
 		suspects = append(suspects, ex.Value)
@@ -182,18 +239,33 @@ func getIgnoreErrorsSuspects(event *Event) []string {
 // ================================
 
 type contextifyFramesIntegration struct {
-	sr              sourceReader
-	contextLines    int
+	sr           sourceReader
+	contextLines int
+	// fileReader reads source files, files are read from disk if nil.
+	fileReader SourceFileReader
+	// fileReaderID identifies fileReader in the keys of the source cache
+	// shared by all clients.
+	fileReaderID    uint64
 	cachedLocations sync.Map
 }
 
+// fileReaderIDs generates the IDs of source file readers.
+var fileReaderIDs uint64
+
 func (cfi *contextifyFramesIntegration) Name() string {
 	return "ContextifyFrames"
 }
 
 func (cfi *contextifyFramesIntegration) SetupOnce(client *Client) {
+	options := client.Options()
+	if options.ContextLines < 0 {
+		return
+	}
 	cfi.sr = newSourceReader()
-	cfi.contextLines = 5
+	cfi.sr.maxFileBytes = limit(options.MaxSourceFileBytes, maxSourceFileBytes)
+	cfi.contextLines = limit(options.ContextLines, 5)
+	cfi.fileReader = options.SourceFileReader
+	cfi.fileReaderID = atomic.AddUint64(&fileReaderIDs, 1)
 
 	client.AddEventProcessor(cfi.processor)
 }
@@ -233,6 +305,16 @@ func (cfi *contextifyFramesIntegration) contextify(frames []Frame) []Frame {
 			continue
 		}
 
+		if cfi.fileReader != nil {
+			frame := frame
+			key := fmt.Sprintf("%d\x00%s\x00%s\x00%s", cfi.fileReaderID, frame.AbsPath, frame.Package, frame.Filename)
+			lines, contextLine := cfi.sr.readContextLinesFunc(key, func() ([]byte, error) {
+				return cfi.fileReader.ReadSourceFile(frame)
+			}, frame.Lineno, cfi.contextLines)
+			contextifiedFrames = append(contextifiedFrames, cfi.addContextLinesToFrame(frame, lines, contextLine))
+			continue
+		}
+
 		var path string
 
 		if cachedPath, ok := cfi.cachedLocations.Load(frame.AbsPath); ok {
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"runtime/debug"
 	"testing"
 
@@ -43,6 +44,20 @@ func TestGetIgnoreErrorsSuspectsMessage(t *testing.T) {
 	assertEqual(t, got, want)
 }
 
+func TestGetIgnoreErrorsSuspectsLogEntry(t *testing.T) {
+	event := &Event{
+		Message: "user 42 failed",
+		LogEntry: &LogEntry{
+			Message:   "user %d failed",
+			Params:    []interface{}{42},
+			Formatted: "user 42 failed",
+		},
+	}
+	got := getIgnoreErrorsSuspects(event)
+	want := []string{"user 42 failed", "user %d failed"}
+	assertEqual(t, got, want)
+}
+
 func TestGetIgnoreErrorsSuspectsException(t *testing.T) {
 	event := &Event{
 		Exception: []Exception{{
@@ -388,3 +403,76 @@ func TestEnvironmentIntegrationDoesNotOverrideExistingContexts(t *testing.T) {
 		t.Errorf(`contexts["custom"] = %#v, want "value"`, contexts["custom"])
 	}
 }
+
+func TestEnvironmentIntegrationMergesTypedContexts(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{
+		Transport: transport,
+		Integrations: func([]Integration) []Integration {
+			return []Integration{new(environmentIntegration)}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := NewScope()
+	scope.SetOSContext(OSContext{Name: "test", Version: "1.0"})
+	scope.SetDeviceContext(DeviceContext{Model: "model"})
+	custom := map[string]interface{}{"foo": "bar"}
+	scope.SetContext("runtime", &RuntimeContext{Name: "custom"})
+	scope.SetContext("custom", custom)
+	hub := NewHub(client, scope)
+	hub.CaptureMessage("test event")
+
+	contexts := transport.lastEvent.Contexts
+
+	assertEqual(t, contexts[OSContextKey], OSContext{Name: "test", Version: "1.0"})
+	assertEqual(t, contexts[DeviceContextKey], DeviceContext{
+		Model:  "model",
+		Arch:   runtime.GOARCH,
+		NumCPU: runtime.NumCPU(),
+	})
+	rt, ok := contexts[RuntimeContextKey].(RuntimeContext)
+	if !ok {
+		t.Fatalf(`contexts["runtime"] = %#v, want RuntimeContext`, contexts[RuntimeContextKey])
+	}
+	assertEqual(t, rt.Name, "custom")
+	assertEqual(t, rt.Version, runtime.Version())
+	assertEqual(t, contexts["custom"], custom)
+}
+
+func TestEnvironmentIntegrationDoesNotModifyScopeContexts(t *testing.T) {
+	iei := new(environmentIntegration)
+	device := map[string]interface{}{"foo": "bar"}
+	event := &Event{Contexts: map[string]interface{}{DeviceContextKey: device}}
+	event = iei.processor(event, &EventHint{})
+
+	assertEqual(t, device, map[string]interface{}{"foo": "bar"})
+	assertEqual(t, event.Contexts[DeviceContextKey], map[string]interface{}{
+		"foo":     "bar",
+		"arch":    runtime.GOARCH,
+		"num_cpu": runtime.NumCPU(),
+	})
+}
+
+func TestContextifyFramesDisabled(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{Transport: transport, ContextLines: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	abspath, err := filepath.Abs("errors_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := NewEvent()
+	event.Exception = []Exception{{Stacktrace: &Stacktrace{Frames: []Frame{{
+		Function: "Trace",
+		AbsPath:  abspath,
+		Lineno:   12,
+		InApp:    true,
+	}}}}}
+	client.CaptureEvent(event, nil, nil)
+
+	assertEqual(t, transport.lastEvent.Exception[0].Stacktrace.Frames[0].ContextLine, "")
+}
@@ -1,11 +1,16 @@
 package sentry
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net"
 	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -58,10 +63,27 @@ type Breadcrumb struct {
 	Data      map[string]interface{} `json:"data,omitempty"`
 	Level     Level                  `json:"level,omitempty"`
 	Timestamp time.Time              `json:"timestamp"`
+
+	// Unknown holds the fields of a decoded breadcrumb that are not modeled
+	// by the struct. They are included when encoding the breadcrumb.
+	Unknown map[string]json.RawMessage `json:"-"`
 }
 
-// TODO: provide constants for known breadcrumb types.
+// Breadcrumb types known by Sentry. Breadcrumbs of these types are rendered
+// in the Sentry UI according to the keys in their Data.
 // See https://develop.sentry.dev/sdk/event-payloads/breadcrumbs/#breadcrumb-types.
+const (
+	BreadcrumbTypeDefault     = "default"
+	BreadcrumbTypeDebug       = "debug"
+	BreadcrumbTypeError       = "error"
+	BreadcrumbTypeInfo        = "info"
+	BreadcrumbTypeHTTP        = "http"
+	BreadcrumbTypeNavigation  = "navigation"
+	BreadcrumbTypeQuery       = "query"
+	BreadcrumbTypeTransaction = "transaction"
+	BreadcrumbTypeUI          = "ui"
+	BreadcrumbTypeUser        = "user"
+)
 
 // MarshalJSON converts the Breadcrumb struct to JSON.
 func (b *Breadcrumb) MarshalJSON() ([]byte, error) {
@@ -78,16 +100,43 @@ func (b *Breadcrumb) MarshalJSON() ([]byte, error) {
 	// methods.
 	type breadcrumb Breadcrumb
 
+	var data []byte
+	var err error
 	if b.Timestamp.IsZero() {
-		return json.Marshal(struct {
+		data, err = json.Marshal(struct {
 			// Embed all of the fields of Breadcrumb.
 			*breadcrumb
 			// Timestamp shadows the original Timestamp field and is meant to
 			// remain nil, triggering the omitempty behavior.
 			Timestamp json.RawMessage `json:"timestamp,omitempty"`
 		}{breadcrumb: (*breadcrumb)(b)})
+	} else {
+		data, err = json.Marshal((*breadcrumb)(b))
+	}
+	if err != nil {
+		return nil, err
 	}
-	return json.Marshal((*breadcrumb)(b))
+	return appendUnknownFields(data, b.Unknown, breadcrumbFields), nil
+}
+
+// UnmarshalJSON converts JSON to the Breadcrumb struct. The timestamp may be
+// a string in RFC 3339 format or a number of seconds since the Unix epoch.
+func (b *Breadcrumb) UnmarshalJSON(data []byte) error {
+	type breadcrumb Breadcrumb
+
+	x := struct {
+		*breadcrumb
+		Timestamp json.RawMessage `json:"timestamp"`
+	}{breadcrumb: (*breadcrumb)(b)}
+	if err := json.Unmarshal(data, &x); err != nil {
+		return err
+	}
+	var err error
+	if b.Timestamp, err = parseTimestamp(x.Timestamp); err != nil {
+		return err
+	}
+	b.Unknown, err = unknownFields(data, breadcrumbFields)
+	return err
 }
 
 // User describes the user associated with an Event. If this is used, at least
@@ -100,10 +149,14 @@ type User struct {
 }
 
 // Request contains information on a HTTP request related to the event.
+//
+// Data holds the request body: structured data, as decoded by encoding/json
+// or a map of form values, for JSON and form-encoded bodies, and a string
+// otherwise.
 type Request struct {
 	URL         string            `json:"url,omitempty"`
 	Method      string            `json:"method,omitempty"`
-	Data        string            `json:"data,omitempty"`
+	Data        interface{}       `json:"data,omitempty"`
 	QueryString string            `json:"query_string,omitempty"`
 	Cookies     string            `json:"cookies,omitempty"`
 	Headers     map[string]string `json:"headers,omitempty"`
@@ -148,6 +201,15 @@ func NewRequest(r *http.Request) *Request {
 	}
 }
 
+// LogEntry describes a message built from a template and parameters. Events
+// with a LogEntry are grouped by the template, so that messages that only
+// differ in their parameters end up in the same issue.
+type LogEntry struct {
+	Message   string        `json:"message,omitempty"`
+	Params    []interface{} `json:"params,omitempty"`
+	Formatted string        `json:"formatted,omitempty"`
+}
+
 // Exception specifies an error that occurred.
 type Exception struct {
 	Type       string      `json:"type,omitempty"`
@@ -155,6 +217,39 @@ type Exception struct {
 	Module     string      `json:"module,omitempty"`
 	ThreadID   string      `json:"thread_id,omitempty"`
 	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+	Mechanism  *Mechanism  `json:"mechanism,omitempty"`
+}
+
+// Mechanism types set by the SDK.
+const (
+	// MechanismTypeGeneric is the type of errors reported explicitly, for
+	// example with CaptureException.
+	MechanismTypeGeneric = "generic"
+	// MechanismTypePanic is the type of errors recovered from a panic.
+	MechanismTypePanic = "panic"
+	// MechanismTypeChained is the type of errors found by unwrapping another
+	// error.
+	MechanismTypeChained = "chained"
+)
+
+// Mechanism describes how an exception was captured, in particular whether
+// it was handled by the application or caused a crash, and how it relates to
+// other exceptions in the same event.
+type Mechanism struct {
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	HelpLink    string `json:"help_link,omitempty"`
+	// Handled is false for errors that were not handled by the application,
+	// such as recovered panics. Nil means unknown.
+	Handled *bool                  `json:"handled,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+	// ExceptionID identifies the exception within the event, and ParentID
+	// refers to the exception it was unwrapped from. Source describes how the
+	// exception was obtained from its parent.
+	ExceptionID      int    `json:"exception_id"`
+	ParentID         *int   `json:"parent_id,omitempty"`
+	Source           string `json:"source,omitempty"`
+	IsExceptionGroup bool   `json:"is_exception_group,omitempty"`
 }
 
 // EventID is a hexadecimal string representing a unique uuid4 for an Event.
@@ -172,6 +267,7 @@ type Event struct {
 	Fingerprint []string               `json:"fingerprint,omitempty"`
 	Level       Level                  `json:"level,omitempty"`
 	Message     string                 `json:"message,omitempty"`
+	LogEntry    *LogEntry              `json:"logentry,omitempty"`
 	Platform    string                 `json:"platform,omitempty"`
 	Release     string                 `json:"release,omitempty"`
 	Sdk         SdkInfo                `json:"sdk,omitempty"`
@@ -186,6 +282,14 @@ type Event struct {
 	Request     *Request               `json:"request,omitempty"`
 	Exception   []Exception            `json:"exception,omitempty"`
 
+	// Attachments are sent along with the event, but are not part of its
+	// JSON encoding.
+	Attachments []*Attachment `json:"-"`
+
+	// Unknown holds the fields of a decoded event that are not modeled by the
+	// struct. They are included when encoding the event.
+	Unknown map[string]json.RawMessage `json:"-"`
+
 	// Experimental: This is part of a beta feature of the SDK. The fields below
 	// are only relevant for transactions.
 	Type           string    `json:"type,omitempty"`
@@ -202,10 +306,67 @@ func (e *Event) MarshalJSON() ([]byte, error) {
 	//
 	// We overcome the limitation and achieve what we want by shadowing fields
 	// and a few type tricks.
+	var data []byte
+	var err error
 	if e.Type == transactionType {
-		return e.transactionMarshalJSON()
+		data, err = e.transactionMarshalJSON()
+	} else {
+		data, err = e.defaultMarshalJSON()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return appendUnknownFields(data, e.Unknown, eventFields), nil
+}
+
+// UnmarshalJSON converts JSON to the Event struct. It is the inverse of
+// MarshalJSON, such that encoding and decoding an event yields an equal event.
+//
+// Timestamps may be strings in RFC 3339 format or numbers of seconds since
+// the Unix epoch. Contexts of the standard types are decoded into the
+// respective structs, such as OSContext, unless they have fields not modeled
+// by the struct. Fields of the event that are not modeled are kept in
+// Unknown.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	// event aliases Event to allow calling json.Unmarshal without an infinite
+	// loop.
+	type event Event
+
+	x := struct {
+		*event
+
+		// The fields below shadow the respective fields in Event, to be
+		// decoded separately.
+
+		Timestamp      json.RawMessage            `json:"timestamp"`
+		StartTimestamp json.RawMessage            `json:"start_timestamp"`
+		Contexts       map[string]json.RawMessage `json:"contexts"`
+	}{event: (*event)(e)}
+	if err := json.Unmarshal(data, &x); err != nil {
+		return err
+	}
+
+	var err error
+	if e.Timestamp, err = parseTimestamp(x.Timestamp); err != nil {
+		return err
+	}
+	if e.StartTimestamp, err = parseTimestamp(x.StartTimestamp); err != nil {
+		return err
+	}
+	if x.Contexts != nil {
+		e.Contexts = make(map[string]interface{}, len(x.Contexts))
+		for k, v := range x.Contexts {
+			if e.Contexts[k], err = decodeContext(k, v); err != nil {
+				return err
+			}
+		}
+	}
+	// The message of events with a LogEntry is only encoded in the LogEntry.
+	if e.Message == "" && e.LogEntry != nil {
+		e.Message = e.LogEntry.Formatted
 	}
-	return e.defaultMarshalJSON()
+	e.Unknown, err = unknownFields(data, eventFields)
+	return err
 }
 
 func (e *Event) defaultMarshalJSON() ([]byte, error) {
@@ -232,6 +393,13 @@ func (e *Event) defaultMarshalJSON() ([]byte, error) {
 	}
 
 	x := errorEvent{event: (*event)(e)}
+	if e.LogEntry != nil && e.Message != "" {
+		// The message is omitted when the event has a LogEntry, which already
+		// carries the formatted message. A shallow copy avoids modifying e.
+		c := *e
+		c.Message = ""
+		x.event = (*event)(&c)
+	}
 	if !e.Timestamp.IsZero() {
 		b, err := e.Timestamp.MarshalJSON()
 		if err != nil {
@@ -292,6 +460,7 @@ func NewEvent() *Event {
 type Thread struct {
 	ID         string      `json:"id,omitempty"`
 	Name       string      `json:"name,omitempty"`
+	State      string      `json:"state,omitempty"`
 	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
 	Crashed    bool        `json:"crashed,omitempty"`
 	Current    bool        `json:"current,omitempty"`
@@ -306,9 +475,153 @@ type EventHint struct {
 	Context            context.Context
 	Request            *http.Request
 	Response           *http.Response
+	// Attachments are sent along with the event, in addition to the
+	// attachments in the scope.
+	Attachments []*Attachment
 }
 
-// TraceContext describes the context of the trace.
+// Keys of the standard contexts in Event.Contexts.
+const (
+	OSContextKey      = "os"
+	RuntimeContextKey = "runtime"
+	DeviceContextKey  = "device"
+	AppContextKey     = "app"
+	CultureContextKey = "culture"
+	TraceContextKey   = "trace"
+)
+
+// OSContext describes the operating system the application runs on. It is
+// stored in Event.Contexts under OSContextKey.
+type OSContext struct {
+	Name           string `json:"name,omitempty"`
+	Version        string `json:"version,omitempty"`
+	Build          string `json:"build,omitempty"`
+	KernelVersion  string `json:"kernel_version,omitempty"`
+	RawDescription string `json:"raw_description,omitempty"`
+}
+
+// RuntimeContext describes the runtime the application runs on. It is stored
+// in Event.Contexts under RuntimeContextKey.
+type RuntimeContext struct {
+	Name           string `json:"name,omitempty"`
+	Version        string `json:"version,omitempty"`
+	Build          string `json:"build,omitempty"`
+	RawDescription string `json:"raw_description,omitempty"`
+
+	// The fields below are specific to the Go runtime.
+
+	GoNumRoutines int   `json:"go_numroutines,omitempty"`
+	GoMaxProcs    int   `json:"go_maxprocs,omitempty"`
+	GoNumCgoCalls int64 `json:"go_numcgocalls,omitempty"`
+}
+
+// DeviceContext describes the device the application runs on. It is stored in
+// Event.Contexts under DeviceContextKey.
+type DeviceContext struct {
+	Name       string `json:"name,omitempty"`
+	Family     string `json:"family,omitempty"`
+	Model      string `json:"model,omitempty"`
+	ModelID    string `json:"model_id,omitempty"`
+	Arch       string `json:"arch,omitempty"`
+	NumCPU     int    `json:"num_cpu,omitempty"`
+	MemorySize int64  `json:"memory_size,omitempty"`
+}
+
+// merge returns a copy of c with the zero fields set from defaults.
+func (c OSContext) merge(defaults OSContext) OSContext {
+	if c.Name == "" {
+		c.Name = defaults.Name
+	}
+	if c.Version == "" {
+		c.Version = defaults.Version
+	}
+	if c.Build == "" {
+		c.Build = defaults.Build
+	}
+	if c.KernelVersion == "" {
+		c.KernelVersion = defaults.KernelVersion
+	}
+	if c.RawDescription == "" {
+		c.RawDescription = defaults.RawDescription
+	}
+	return c
+}
+
+// merge returns a copy of c with the zero fields set from defaults.
+func (c RuntimeContext) merge(defaults RuntimeContext) RuntimeContext {
+	if c.Name == "" {
+		c.Name = defaults.Name
+	}
+	if c.Version == "" {
+		c.Version = defaults.Version
+	}
+	if c.Build == "" {
+		c.Build = defaults.Build
+	}
+	if c.RawDescription == "" {
+		c.RawDescription = defaults.RawDescription
+	}
+	if c.GoNumRoutines == 0 {
+		c.GoNumRoutines = defaults.GoNumRoutines
+	}
+	if c.GoMaxProcs == 0 {
+		c.GoMaxProcs = defaults.GoMaxProcs
+	}
+	if c.GoNumCgoCalls == 0 {
+		c.GoNumCgoCalls = defaults.GoNumCgoCalls
+	}
+	return c
+}
+
+// merge returns a copy of c with the zero fields set from defaults.
+func (c DeviceContext) merge(defaults DeviceContext) DeviceContext {
+	if c.Name == "" {
+		c.Name = defaults.Name
+	}
+	if c.Family == "" {
+		c.Family = defaults.Family
+	}
+	if c.Model == "" {
+		c.Model = defaults.Model
+	}
+	if c.ModelID == "" {
+		c.ModelID = defaults.ModelID
+	}
+	if c.Arch == "" {
+		c.Arch = defaults.Arch
+	}
+	if c.NumCPU == 0 {
+		c.NumCPU = defaults.NumCPU
+	}
+	if c.MemorySize == 0 {
+		c.MemorySize = defaults.MemorySize
+	}
+	return c
+}
+
+// AppContext describes the application. It is stored in Event.Contexts under
+// AppContextKey.
+type AppContext struct {
+	StartTime  *time.Time `json:"app_start_time,omitempty"`
+	BuildType  string     `json:"build_type,omitempty"`
+	Identifier string     `json:"app_identifier,omitempty"`
+	Name       string     `json:"app_name,omitempty"`
+	Version    string     `json:"app_version,omitempty"`
+	Build      string     `json:"app_build,omitempty"`
+}
+
+// CultureContext describes the locale settings of the user or the
+// application. It is stored in Event.Contexts under CultureContextKey.
+type CultureContext struct {
+	Calendar       string `json:"calendar,omitempty"`
+	DisplayName    string `json:"display_name,omitempty"`
+	Locale         string `json:"locale,omitempty"`
+	Is24HourFormat *bool  `json:"is_24_hour_format,omitempty"`
+	Timezone       string `json:"timezone,omitempty"`
+}
+
+// TraceContext describes the context of the trace. It is stored in
+// Event.Contexts under TraceContextKey.
 //
 // Experimental: This is part of a beta feature of the SDK.
 type TraceContext struct {
@@ -334,3 +647,156 @@ type Span struct {
 	EndTimestamp   time.Time              `json:"timestamp"`
 	Data           map[string]interface{} `json:"data,omitempty"`
 }
+
+// UnmarshalJSON converts JSON to the Span struct. Timestamps may be strings in
+// RFC 3339 format or numbers of seconds since the Unix epoch.
+func (s *Span) UnmarshalJSON(data []byte) error {
+	type span Span
+
+	x := struct {
+		*span
+		StartTimestamp json.RawMessage `json:"start_timestamp"`
+		EndTimestamp   json.RawMessage `json:"timestamp"`
+	}{span: (*span)(s)}
+	if err := json.Unmarshal(data, &x); err != nil {
+		return err
+	}
+	var err error
+	if s.StartTimestamp, err = parseTimestamp(x.StartTimestamp); err != nil {
+		return err
+	}
+	s.EndTimestamp, err = parseTimestamp(x.EndTimestamp)
+	return err
+}
+
+// eventFields and breadcrumbFields are the names of the JSON fields modeled
+// by Event and Breadcrumb.
+var (
+	eventFields      = jsonFields(reflect.TypeOf(Event{}))
+	breadcrumbFields = jsonFields(reflect.TypeOf(Breadcrumb{}))
+)
+
+// jsonFields returns the set of JSON field names of a struct type.
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = true
+	}
+	return fields
+}
+
+// unknownFields returns the fields of the JSON object in data that are not in
+// known, or nil if there are none.
+func unknownFields(data []byte, known map[string]bool) (map[string]json.RawMessage, error) {
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	var unknown map[string]json.RawMessage
+	for k, v := range all {
+		if known[k] {
+			continue
+		}
+		if unknown == nil {
+			unknown = make(map[string]json.RawMessage)
+		}
+		unknown[k] = v
+	}
+	return unknown, nil
+}
+
+// appendUnknownFields adds the unknown fields to the JSON object in data, in
+// order of their names. Fields in known are skipped, so that unknown fields
+// never override modeled fields.
+func appendUnknownFields(data []byte, unknown map[string]json.RawMessage, known map[string]bool) []byte {
+	if len(unknown) == 0 {
+		return data
+	}
+	keys := make([]string, 0, len(unknown))
+	for k := range unknown {
+		if !known[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	b.Write(data[:len(data)-1])
+	for _, k := range keys {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(k)
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(unknown[k])
+	}
+	b.WriteByte('}')
+	return b.Bytes()
+}
+
+// parseTimestamp parses a JSON timestamp, either a string in RFC 3339 format
+// or a number of seconds since the Unix epoch. Strings without a time zone
+// are taken to be in UTC. A missing or null timestamp is the zero time.
+func parseTimestamp(data json.RawMessage) (time.Time, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return time.Time{}, nil
+	}
+	if data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return time.Time{}, err
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			// Timestamps without a time zone are in UTC.
+			var err2 error
+			if t, err2 = time.Parse("2006-01-02T15:04:05.999999999", s); err2 != nil {
+				return time.Time{}, fmt.Errorf("invalid timestamp %s: %v", data, err)
+			}
+		}
+		return t, nil
+	}
+	f, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %s", data)
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e9))).UTC(), nil
+}
+
+// decodeContext decodes the context with the given key. Contexts of the
+// standard types are decoded into the respective structs, unless that would
+// lose fields. Other contexts are decoded as generic JSON values.
+func decodeContext(key string, data json.RawMessage) (interface{}, error) {
+	var typed interface{}
+	switch key {
+	case OSContextKey:
+		typed = &OSContext{}
+	case RuntimeContextKey:
+		typed = &RuntimeContext{}
+	case DeviceContextKey:
+		typed = &DeviceContext{}
+	case AppContextKey:
+		typed = &AppContext{}
+	case CultureContextKey:
+		typed = &CultureContext{}
+	case TraceContextKey:
+		typed = &TraceContext{}
+	}
+	if typed != nil {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(typed); err == nil {
+			// Store values, not pointers, like the Scope helpers do.
+			return reflect.ValueOf(typed).Elem().Interface(), nil
+		}
+	}
+	var v interface{}
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
@@ -25,7 +25,6 @@ func TestNewRequest(t *testing.T) {
 	want := &Request{
 		URL:         "http://example.com/test/",
 		Method:      "POST",
-		Data:        "",
 		QueryString: "q=sentry",
 		Cookies:     "",
 		Headers: map[string]string{
@@ -67,6 +66,55 @@ func TestEventMarshalJSON(t *testing.T) {
 	}
 }
 
+func TestEventMarshalJSONLogEntry(t *testing.T) {
+	event := &Event{
+		Message: "user 42 failed",
+		LogEntry: &LogEntry{
+			Message:   "user %d failed",
+			Params:    []interface{}{42},
+			Formatted: "user 42 failed",
+		},
+	}
+
+	got, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The message is sent only as part of the log entry.
+	want := `{"logentry":{"message":"user %d failed","params":[42],"formatted":"user 42 failed"},"sdk":{},"user":{}}`
+
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("Event mismatch (-want +got):\n%s", diff)
+	}
+	assertEqual(t, event.Message, "user 42 failed")
+}
+
+func TestContextsMarshalJSON(t *testing.T) {
+	start := time.Unix(5, 0).UTC()
+	is24 := true
+	event := &Event{Contexts: map[string]interface{}{
+		AppContextKey:     AppContext{StartTime: &start, Name: "app", Version: "1.2.3"},
+		CultureContextKey: CultureContext{Locale: "pt-BR", Is24HourFormat: &is24, Timezone: "America/Sao_Paulo"},
+		DeviceContextKey:  DeviceContext{Arch: "amd64", NumCPU: 8},
+	}}
+
+	got, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"contexts":{` +
+		`"app":{"app_start_time":"1970-01-01T00:00:05Z","app_name":"app","app_version":"1.2.3"},` +
+		`"culture":{"locale":"pt-BR","is_24_hour_format":true,"timezone":"America/Sao_Paulo"},` +
+		`"device":{"arch":"amd64","num_cpu":8}` +
+		`},"sdk":{},"user":{}}`
+
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("Event mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestStructSnapshots(t *testing.T) {
 	testSpan := &Span{
 		TraceID:      "d6c4f03650bd47699ec65c84352b6208",
@@ -202,3 +202,117 @@ func ReadOrGenerateGoldenFile(t *testing.T, path string, bytes []byte) string {
 	}
 	return string(b)
 }
+
+func TestUnmarshalJSONGoldenFiles(t *testing.T) {
+	tests := []struct {
+		dir string
+		new func() interface{}
+	}{
+		{"event", func() interface{} { return new(Event) }},
+		{"transaction", func() interface{} { return new(Event) }},
+		{"breadcrumb", func() interface{} { return new(Breadcrumb) }},
+	}
+	for _, tt := range tests {
+		paths, err := filepath.Glob(filepath.Join("testdata", "json", tt.dir, "*.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range paths {
+			tt, path := tt, path
+			t.Run(path, func(t *testing.T) {
+				want, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				v := tt.new()
+				if err := json.Unmarshal(want, v); err != nil {
+					t.Fatal(err)
+				}
+				var buf bytes.Buffer
+				enc := json.NewEncoder(&buf)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(v); err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(string(want), buf.String()); diff != "" {
+					t.Errorf("JSON round-trip mismatch (-want +got):\n%s", diff)
+				}
+			})
+		}
+	}
+}
+
+func TestEventJSONRoundTrip(t *testing.T) {
+	event := &Event{
+		EventID:   "0123456789abcdef",
+		Level:     LevelError,
+		Message:   "user 42 failed",
+		LogEntry:  &LogEntry{Message: "user %d failed", Params: []interface{}{"42"}, Formatted: "user 42 failed"},
+		Timestamp: goReleaseDate.In(utcMinusTwo),
+		Breadcrumbs: []*Breadcrumb{{
+			Type:      BreadcrumbTypeHTTP,
+			Data:      map[string]interface{}{"url": "https://example.com"},
+			Timestamp: goReleaseDate,
+			Unknown:   map[string]json.RawMessage{"origin": json.RawMessage(`"auto"`)},
+		}},
+		Contexts: map[string]interface{}{
+			OSContextKey:      OSContext{Name: "linux"},
+			RuntimeContextKey: RuntimeContext{Name: "go", Version: "go1.13", GoMaxProcs: 4},
+			TraceContextKey:   TraceContext{TraceID: "d6c4f03650bd47699ec65c84352b6208", SpanID: "1cc4b26ab9094ef0"},
+			// Not decoded into DeviceContext, which would lose the battery.
+			DeviceContextKey: map[string]interface{}{"arch": "amd64", "battery_level": "full"},
+			"custom":         "value",
+		},
+		Exception: []Exception{{
+			Type:      "*errors.errorString",
+			Value:     "failed",
+			Mechanism: &Mechanism{Type: MechanismTypeGeneric, Handled: boolPtr(true)},
+		}},
+		Unknown: map[string]json.RawMessage{
+			"debug_meta": json.RawMessage(`{"images":[]}`),
+		},
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Event
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(event, &got); diff != "" {
+		t.Errorf("Event mismatch (-want +got):\n%s", diff)
+	}
+
+	b2, err := json.Marshal(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(b), string(b2)); diff != "" {
+		t.Errorf("JSON mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEventUnmarshalJSONNumericTimestamps(t *testing.T) {
+	data := `{
+		"type": "transaction",
+		"start_timestamp": 1257894000,
+		"timestamp": 1257894060.5,
+		"breadcrumbs": [{"message": "b", "timestamp": "2009-11-10T23:00:00"}],
+		"spans": [{"trace_id": "t", "span_id": "s", "start_timestamp": 1257894000.25, "timestamp": "2009-11-10T23:01:00Z"}]
+	}`
+	var event Event
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, event.StartTimestamp, goReleaseDate)
+	assertEqual(t, event.Timestamp, goReleaseDate.Add(time.Minute+500*time.Millisecond))
+	assertEqual(t, event.Breadcrumbs[0].Timestamp, goReleaseDate)
+	assertEqual(t, event.Spans[0].StartTimestamp, goReleaseDate.Add(250*time.Millisecond))
+	assertEqual(t, event.Spans[0].EndTimestamp, goReleaseDate.Add(time.Minute))
+
+	if err := json.Unmarshal([]byte(`{"timestamp":"yesterday"}`), &event); err == nil {
+		t.Error("got nil error for invalid timestamp")
+	}
+}
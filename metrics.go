@@ -0,0 +1,146 @@
+package sentry
+
+import (
+	"expvar"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Reasons for dropping events, used as keys of Metrics.EventsDropped.
+const (
+	dropReasonSampleRate     = "sample_rate"
+	dropReasonEventProcessor = "event_processor"
+	dropReasonBeforeSend     = "before_send"
+	dropReasonDuplicate      = "duplicate"
+	dropReasonNoTransport    = "no_transport"
+	dropReasonMarshal        = "marshal_error"
+	dropReasonQueueFull      = "queue_full"
+	dropReasonRateLimit      = "rate_limit"
+	dropReasonNetworkError   = "network_error"
+	dropReasonRejected       = "rejected"
+)
+
+// Metrics is a snapshot of the internal counters and gauges of the SDK. The
+// values are aggregated for all clients and transports in the process.
+//
+// The same data is published through the expvar package under the name
+// "sentry-go", unless another package already published a variable with
+// that name.
+type Metrics struct {
+	// EventsCaptured is the number of events passed to a Client for
+	// processing.
+	EventsCaptured int64
+	// EventsDropped is the number of events dropped before reaching Sentry,
+	// by reason. For example, "sample_rate", "before_send" or "queue_full".
+	EventsDropped map[string]int64
+	// QueueDepth is the number of events waiting to be sent by an
+	// HTTPTransport.
+	QueueDepth int64
+	// Requests is the number of HTTP requests sent to Sentry, including
+	// requests that failed.
+	Requests int64
+	// SendLatency is the total time spent in HTTP requests to Sentry. The
+	// average latency is SendLatency divided by Requests.
+	SendLatency time.Duration
+	// HTTPStatusCodes is the number of responses from Sentry, by status code.
+	HTTPStatusCodes map[int]int64
+	// BytesSent is the total size of request bodies sent to Sentry.
+	BytesSent int64
+	// RateLimitBackoff is the total time transports were instructed to stop
+	// sending events because of rate limiting.
+	RateLimitBackoff time.Duration
+}
+
+// expvarName is the name of the expvar variable publishing the metrics.
+const expvarName = "sentry-go"
+
+// sdkMetrics holds the live counters behind Metrics.
+type sdkMetrics struct {
+	eventsCaptured   expvar.Int
+	eventsDropped    expvar.Map
+	queueDepth       expvar.Int
+	requests         expvar.Int
+	sendLatency      expvar.Int // nanoseconds
+	httpStatusCodes  expvar.Map
+	bytesSent        expvar.Int
+	rateLimitBackoff expvar.Int // nanoseconds
+}
+
+// metrics is the process-wide instance of sdkMetrics.
+var metrics = newSDKMetrics()
+
+func newSDKMetrics() *sdkMetrics {
+	m := new(sdkMetrics)
+	m.eventsDropped.Init()
+	m.httpStatusCodes.Init()
+
+	// expvar.NewMap panics if the name is taken, which must not prevent
+	// programs from starting.
+	if expvar.Get(expvarName) != nil {
+		return m
+	}
+	published := expvar.NewMap(expvarName)
+	published.Set("events_captured", &m.eventsCaptured)
+	published.Set("events_dropped", &m.eventsDropped)
+	published.Set("queue_depth", &m.queueDepth)
+	published.Set("requests", &m.requests)
+	published.Set("send_latency_ns", &m.sendLatency)
+	published.Set("http_status_codes", &m.httpStatusCodes)
+	published.Set("bytes_sent", &m.bytesSent)
+	published.Set("rate_limit_backoff_ns", &m.rateLimitBackoff)
+	return m
+}
+
+// dropped records that an event was dropped for the given reason.
+func (m *sdkMetrics) dropped(reason string) {
+	m.eventsDropped.Add(reason, 1)
+}
+
+// requestDone records the outcome of an HTTP request to Sentry. The response
+// is nil if the request failed.
+func (m *sdkMetrics) requestDone(size int64, latency time.Duration, response *http.Response) {
+	m.requests.Add(1)
+	m.sendLatency.Add(int64(latency))
+	if size > 0 {
+		m.bytesSent.Add(size)
+	}
+	if response == nil {
+		m.dropped(dropReasonNetworkError)
+		return
+	}
+	m.httpStatusCodes.Add(strconv.Itoa(response.StatusCode), 1)
+	if response.StatusCode >= http.StatusBadRequest {
+		m.dropped(dropReasonRejected)
+	}
+}
+
+// ReadMetrics returns a snapshot of the internal metrics of the SDK.
+//
+// Comparing EventsCaptured, EventsDropped and HTTPStatusCodes over time tells
+// apart a drop in errors in the application from events that the SDK did not
+// deliver to Sentry.
+func ReadMetrics() Metrics {
+	snapshot := Metrics{
+		EventsCaptured:   metrics.eventsCaptured.Value(),
+		EventsDropped:    make(map[string]int64),
+		QueueDepth:       metrics.queueDepth.Value(),
+		Requests:         metrics.requests.Value(),
+		SendLatency:      time.Duration(metrics.sendLatency.Value()),
+		HTTPStatusCodes:  make(map[int]int64),
+		BytesSent:        metrics.bytesSent.Value(),
+		RateLimitBackoff: time.Duration(metrics.rateLimitBackoff.Value()),
+	}
+	metrics.eventsDropped.Do(func(kv expvar.KeyValue) {
+		if v, ok := kv.Value.(*expvar.Int); ok {
+			snapshot.EventsDropped[kv.Key] = v.Value()
+		}
+	})
+	metrics.httpStatusCodes.Do(func(kv expvar.KeyValue) {
+		code, err := strconv.Atoi(kv.Key)
+		if v, ok := kv.Value.(*expvar.Int); ok && err == nil {
+			snapshot.HTTPStatusCodes[code] = v.Value()
+		}
+	})
+	return snapshot
+}
@@ -0,0 +1,138 @@
+package sentry
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsClientDrops(t *testing.T) {
+	before := ReadMetrics()
+
+	client, err := NewClient(ClientOptions{
+		BeforeSend: func(event *Event, hint *EventHint) *Event {
+			if event.Message == "drop" {
+				return nil
+			}
+			return event
+		},
+		Transport: &TransportMock{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.AddEventProcessor(func(event *Event, hint *EventHint) *Event {
+		if event.Message == "processor" {
+			return nil
+		}
+		return event
+	})
+	client.CaptureMessage("keep", nil, nil)
+	client.CaptureMessage("drop", nil, nil)
+	client.CaptureMessage("processor", nil, nil)
+
+	after := ReadMetrics()
+	assertEqual(t, after.EventsCaptured-before.EventsCaptured, int64(3))
+	assertEqual(t, after.EventsDropped[dropReasonBeforeSend]-before.EventsDropped[dropReasonBeforeSend], int64(1))
+	assertEqual(t, after.EventsDropped[dropReasonEventProcessor]-before.EventsDropped[dropReasonEventProcessor], int64(1))
+}
+
+func TestMetricsHTTPSyncTransport(t *testing.T) {
+	statusCodes := []int{http.StatusOK, http.StatusInternalServerError, http.StatusTooManyRequests}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if statusCodes[requests] == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "60")
+		}
+		w.WriteHeader(statusCodes[requests])
+		requests++
+	}))
+	defer server.Close()
+
+	before := ReadMetrics()
+
+	transport := NewHTTPSyncTransport()
+	transport.Configure(ClientOptions{
+		Dsn: strings.Replace(server.URL, "://", "://key@", 1) + "/1",
+	})
+	for i := 0; i < 4; i++ {
+		e := NewEvent()
+		e.EventID = EventID(uuid())
+		transport.SendEvent(e)
+	}
+
+	// Metrics are global and transports from other tests may still be sending
+	// events in the background. Only values that are unique to this test are
+	// compared exactly.
+	after := ReadMetrics()
+	assertEqual(t, requests, 3)
+	if n := after.Requests - before.Requests; n < 3 {
+		t.Errorf("Requests = %d, want at least 3", n)
+	}
+	for _, code := range statusCodes[1:] {
+		assertEqual(t, after.HTTPStatusCodes[code]-before.HTTPStatusCodes[code], int64(1), "status %d", code)
+	}
+	assertEqual(t, after.EventsDropped[dropReasonRejected]-before.EventsDropped[dropReasonRejected], int64(2))
+	if n := after.EventsDropped[dropReasonRateLimit] - before.EventsDropped[dropReasonRateLimit]; n < 1 {
+		t.Errorf("EventsDropped[%q] = %d, want at least 1", dropReasonRateLimit, n)
+	}
+	assertEqual(t, after.RateLimitBackoff-before.RateLimitBackoff, 60*time.Second)
+	if after.BytesSent <= before.BytesSent {
+		t.Errorf("BytesSent = %d, want more than %d", after.BytesSent, before.BytesSent)
+	}
+	if after.SendLatency <= before.SendLatency {
+		t.Errorf("SendLatency = %s, want more than %s", after.SendLatency, before.SendLatency)
+	}
+}
+
+func TestMetricsQueueDepth(t *testing.T) {
+	server := newTestHTTPServer(t)
+	defer server.Close()
+
+	transport := NewHTTPTransport()
+	transport.Configure(ClientOptions{
+		Dsn:        fmt.Sprintf("https://test@%s/1", server.Listener.Addr()),
+		HTTPClient: server.Client(),
+	})
+
+	before := ReadMetrics()
+	transport.SendEvent(NewEvent())
+	transport.SendEvent(NewEvent())
+	if depth := ReadMetrics().QueueDepth - before.QueueDepth; depth < 1 {
+		t.Errorf("QueueDepth = %d, want at least 1", depth)
+	}
+	server.Unblock()
+	server.Unblock()
+	if !transport.Flush(time.Second) {
+		t.Fatal("Flush timed out")
+	}
+	// Other transports may still be draining their queues, so only check that
+	// this transport did not leave anything behind.
+	if depth := ReadMetrics().QueueDepth; depth > before.QueueDepth {
+		t.Errorf("QueueDepth = %d, want at most %d", depth, before.QueueDepth)
+	}
+}
+
+func TestMetricsExpvarNameTaken(t *testing.T) {
+	// The metrics of the package were published already, so the name is
+	// taken. Creating metrics again must not panic.
+	m := newSDKMetrics()
+	m.eventsCaptured.Add(1)
+	assertEqual(t, expvar.Get(expvarName) != nil, true)
+}
+
+func TestMetricsExpvar(t *testing.T) {
+	v := expvar.Get(expvarName)
+	if v == nil {
+		t.Fatalf("expvar %q not published", expvarName)
+	}
+	for _, name := range []string{"events_captured", "events_dropped", "queue_depth", "http_status_codes"} {
+		if !strings.Contains(v.String(), fmt.Sprintf("%q", name)) {
+			t.Errorf("expvar does not contain %q: %s", name, v)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package sentry
+
+import (
+	"net/http"
+)
+
+// piiHeaders are the request headers removed from events unless the
+// SendDefaultPII option is enabled. They carry credentials, session
+// identifiers or the IP address of the client.
+var piiHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+	"X-Csrf-Token",
+	"X-Xsrf-Token",
+	"Forwarded",
+	"X-Forwarded-For",
+	"X-Real-Ip",
+	"True-Client-Ip",
+	"Cf-Connecting-Ip",
+}
+
+// removePII strips personally identifiable information from the event:
+// cookies, authentication headers and client IP addresses of the request, and
+// the email and IP address of the user. Headers in denylist are removed too.
+// When sendDefaultPII is true, only the headers in denylist are removed.
+func removePII(event *Event, sendDefaultPII bool, denylist []string) {
+	if !sendDefaultPII {
+		event.User.Email = ""
+		event.User.IPAddress = ""
+	}
+
+	if event.Request == nil {
+		return
+	}
+
+	deny := make(map[string]bool, len(piiHeaders)+len(denylist))
+	for _, h := range denylist {
+		deny[http.CanonicalHeaderKey(h)] = true
+	}
+	if !sendDefaultPII {
+		for _, h := range piiHeaders {
+			deny[h] = true
+		}
+	}
+
+	// Work on a copy of the request.
+	r := *event.Request
+	if !sendDefaultPII {
+		r.Cookies = ""
+		if r.Env != nil {
+			env := make(map[string]string, len(r.Env))
+			for k, v := range r.Env {
+				if k != "REMOTE_ADDR" && k != "REMOTE_PORT" {
+					env[k] = v
+				}
+			}
+			r.Env = env
+		}
+	}
+	if r.Headers != nil && len(deny) > 0 {
+		headers := make(map[string]string, len(r.Headers))
+		for k, v := range r.Headers {
+			if !deny[http.CanonicalHeaderKey(k)] {
+				headers[k] = v
+			}
+		}
+		r.Headers = headers
+	}
+	event.Request = &r
+}
@@ -0,0 +1,78 @@
+package sentry
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRemovePII(t *testing.T) {
+	newEvent := func() *Event {
+		r := httptest.NewRequest("GET", "/foo", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		r.Header.Set("Cookie", "session=secret")
+		r.Header.Set("X-Forwarded-For", "203.0.113.1")
+		r.Header.Set("X-Custom", "value")
+		r.Header.Set("Accept", "*/*")
+		event := NewEvent()
+		event.Request = NewRequest(r)
+		event.User = User{ID: "1", Email: "user@example.com", IPAddress: "203.0.113.1"}
+		return event
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		event := newEvent()
+		request := event.Request
+		removePII(event, false, []string{"x-custom"})
+
+		assertEqual(t, event.User, User{ID: "1"})
+		assertEqual(t, event.Request.Cookies, "")
+		assertEqual(t, event.Request.Env, map[string]string{})
+		assertEqual(t, event.Request.Headers, map[string]string{
+			"Accept": "*/*",
+			"Host":   "example.com",
+		})
+		// The original request is left untouched.
+		assertEqual(t, request.Cookies, "session=secret")
+		assertEqual(t, request.Headers["Authorization"], "Bearer secret")
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		event := newEvent()
+		removePII(event, true, []string{"x-custom"})
+
+		assertEqual(t, event.User, User{ID: "1", Email: "user@example.com", IPAddress: "203.0.113.1"})
+		assertEqual(t, event.Request.Cookies, "session=secret")
+		assertEqual(t, event.Request.Env["REMOTE_ADDR"], "192.0.2.1")
+		assertEqual(t, event.Request.Headers, map[string]string{
+			"Accept":          "*/*",
+			"Authorization":   "Bearer secret",
+			"Cookie":          "session=secret",
+			"Host":            "example.com",
+			"X-Forwarded-For": "203.0.113.1",
+		})
+	})
+}
+
+func TestSendDefaultPII(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := NewScope()
+	r := httptest.NewRequest("GET", "/foo", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	scope.SetRequest(r)
+	scope.SetUser(User{Email: "user@example.com"})
+
+	client.CaptureMessage("message", nil, scope)
+
+	event := transport.lastEvent
+	if event == nil {
+		t.Fatal("missing event")
+	}
+	if _, ok := event.Request.Headers["Authorization"]; ok {
+		t.Error("Authorization header not removed")
+	}
+	assertEqual(t, event.User.Email, "")
+}
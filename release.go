@@ -0,0 +1,78 @@
+package sentry
+
+import (
+	"os"
+	"runtime/debug"
+)
+
+// releaseEnvVars are environment variables set by CI and PaaS providers to the
+// revision being built or deployed, in order of precedence.
+var releaseEnvVars = []string{
+	// Heroku
+	"SOURCE_VERSION",
+	"HEROKU_SLUG_COMMIT",
+	// GitHub Actions
+	"GITHUB_SHA",
+	// GitLab CI
+	"CI_COMMIT_SHA",
+	// CircleCI
+	"CIRCLE_SHA1",
+	// Travis CI
+	"TRAVIS_COMMIT",
+	// Bitbucket Pipelines
+	"BITBUCKET_COMMIT",
+	// Buildkite
+	"BUILDKITE_COMMIT",
+	// AWS CodeBuild
+	"CODEBUILD_RESOLVED_SOURCE_VERSION",
+	// Vercel
+	"VERCEL_GIT_COMMIT_SHA",
+	// Render
+	"RENDER_GIT_COMMIT",
+	// Jenkins
+	"GIT_COMMIT",
+}
+
+// defaultRelease returns the release of the program, used when the Release
+// option and the SENTRY_RELEASE environment variable are not set. It is, in
+// order of precedence, the version control revision embedded in the binary,
+// the version of the main module or the revision set by CI and PaaS
+// providers in the environment. It returns the empty string if none is
+// available.
+func defaultRelease() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if release := releaseFromBuildInfo(info); release != "" {
+			return release
+		}
+	}
+	return releaseFromEnvironment()
+}
+
+// releaseFromBuildInfo returns the version control revision recorded in the
+// build info, with a "+dirty" suffix if the working tree had local
+// modifications, or else the version of the main module.
+func releaseFromBuildInfo(info *debug.BuildInfo) string {
+	if revision, modified := vcsRevision(info); revision != "" {
+		if modified {
+			revision += "+dirty"
+		}
+		return revision
+	}
+	// The main module has version "(devel)" when built from its own source
+	// tree instead of installed from a module proxy.
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	return ""
+}
+
+// releaseFromEnvironment returns the revision set by CI and PaaS providers in
+// the environment.
+func releaseFromEnvironment() string {
+	for _, name := range releaseEnvVars {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
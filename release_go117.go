@@ -0,0 +1,13 @@
+//go:build !go1.18
+// +build !go1.18
+
+package sentry
+
+import "runtime/debug"
+
+// vcsRevision returns the version control revision recorded in the build info
+// and whether the working tree had local modifications. Version control
+// information is only recorded since Go 1.18.
+func vcsRevision(info *debug.BuildInfo) (revision string, modified bool) {
+	return "", false
+}
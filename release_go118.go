@@ -0,0 +1,20 @@
+//go:build go1.18
+// +build go1.18
+
+package sentry
+
+import "runtime/debug"
+
+// vcsRevision returns the version control revision recorded in the build info
+// and whether the working tree had local modifications.
+func vcsRevision(info *debug.BuildInfo) (revision string, modified bool) {
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	return revision, modified
+}
@@ -0,0 +1,24 @@
+//go:build go1.18
+// +build go1.18
+
+package sentry
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestReleaseFromBuildInfoVCS(t *testing.T) {
+	info := &debug.BuildInfo{
+		Main: debug.Module{Path: "example.com/app", Version: "v1.2.3"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs", Value: "git"},
+			{Key: "vcs.revision", Value: "4d0e8ae0d6b2f4d3b1a1b6e8c1a2b3c4d5e6f7a8"},
+			{Key: "vcs.modified", Value: "false"},
+		},
+	}
+	assertEqual(t, releaseFromBuildInfo(info), "4d0e8ae0d6b2f4d3b1a1b6e8c1a2b3c4d5e6f7a8")
+
+	info.Settings[2].Value = "true"
+	assertEqual(t, releaseFromBuildInfo(info), "4d0e8ae0d6b2f4d3b1a1b6e8c1a2b3c4d5e6f7a8+dirty")
+}
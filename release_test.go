@@ -0,0 +1,93 @@
+package sentry
+
+import (
+	"os"
+	"runtime/debug"
+	"testing"
+)
+
+// setenv sets the environment variables in env, with an empty value meaning
+// unset, and returns a function that restores their previous values.
+func setenv(t *testing.T, env map[string]string) (restore func()) {
+	t.Helper()
+	saved := make(map[string]*string, len(env))
+	for k, v := range env {
+		if old, ok := os.LookupEnv(k); ok {
+			saved[k] = &old
+		} else {
+			saved[k] = nil
+		}
+		var err error
+		if v == "" {
+			err = os.Unsetenv(k)
+		} else {
+			err = os.Setenv(k, v)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return func() {
+		for k, v := range saved {
+			if v == nil {
+				os.Unsetenv(k)
+			} else {
+				os.Setenv(k, *v)
+			}
+		}
+	}
+}
+
+func TestReleaseFromBuildInfo(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"v1.2.3", "v1.2.3"},
+		{"(devel)", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		info := &debug.BuildInfo{Main: debug.Module{Path: "example.com/app", Version: tt.version}}
+		assertEqual(t, releaseFromBuildInfo(info), tt.want, tt.version)
+	}
+}
+
+func TestReleaseFromEnvironment(t *testing.T) {
+	env := make(map[string]string, len(releaseEnvVars))
+	for _, name := range releaseEnvVars {
+		env[name] = ""
+	}
+	defer setenv(t, env)()
+
+	assertEqual(t, releaseFromEnvironment(), "")
+
+	defer setenv(t, map[string]string{"GITHUB_SHA": "github"})()
+	assertEqual(t, releaseFromEnvironment(), "github")
+
+	defer setenv(t, map[string]string{"HEROKU_SLUG_COMMIT": "heroku"})()
+	assertEqual(t, releaseFromEnvironment(), "heroku")
+}
+
+func TestNewClientDetectsRelease(t *testing.T) {
+	env := map[string]string{"SENTRY_RELEASE": ""}
+	for _, name := range releaseEnvVars {
+		env[name] = ""
+	}
+	env["GITHUB_SHA"] = "0123456789abcdef"
+	defer setenv(t, env)()
+
+	client, err := NewClient(ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Test binaries have no version control information and the main module
+	// is in development, so the release comes from the environment.
+	assertEqual(t, client.Options().Release, "0123456789abcdef")
+
+	client, err = NewClient(ClientOptions{Release: "configured"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, client.Options().Release, "configured")
+}
@@ -2,9 +2,13 @@ package sentry
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
 )
@@ -43,7 +47,11 @@ type Scope struct {
 		// size.
 		Overflow() bool
 	}
+	// requestBodySize is the MaxRequestBodySize option of the client bound
+	// with the scope to a Hub.
+	requestBodySize RequestBodySize
 	eventProcessors []EventProcessor
+	attachments     []*Attachment
 }
 
 // NewScope creates a new Scope.
@@ -94,6 +102,9 @@ func (scope *Scope) SetUser(user User) {
 }
 
 // SetRequest sets the request for the current scope.
+//
+// The request body is buffered as it is read, up to the MaxRequestBodySize
+// option of the client bound with the scope to a Hub.
 func (scope *Scope) SetRequest(r *http.Request) {
 	scope.mu.Lock()
 	defer scope.mu.Unlock()
@@ -104,15 +115,19 @@ func (scope *Scope) SetRequest(r *http.Request) {
 		return
 	}
 
+	max, ok := scope.requestBodySize.maxBytes()
+	if !ok {
+		return
+	}
 	// Don't buffer request body if we know it is oversized.
-	if r.ContentLength > maxRequestBodyBytes {
+	if r.ContentLength > int64(max) {
 		return
 	}
 	// Don't buffer if there is no body.
 	if r.Body == nil || r.Body == http.NoBody {
 		return
 	}
-	buf := &limitedBuffer{Capacity: maxRequestBodyBytes}
+	buf := &limitedBuffer{Capacity: max}
 	r.Body = readCloser{
 		Reader: io.TeeReader(r.Body, buf),
 		Closer: r.Body,
@@ -129,7 +144,11 @@ func (scope *Scope) SetRequestBody(b []byte) {
 	scope.mu.Lock()
 	defer scope.mu.Unlock()
 
-	capacity := maxRequestBodyBytes
+	capacity, ok := scope.requestBodySize.maxBytes()
+	if !ok {
+		scope.requestBody = nil
+		return
+	}
 	overflow := false
 	if len(b) > capacity {
 		overflow = true
@@ -142,10 +161,97 @@ func (scope *Scope) SetRequestBody(b []byte) {
 	}
 }
 
+// setRequestBodySize sets the maximum size of request bodies buffered by
+// SetRequest and SetRequestBody.
+func (scope *Scope) setRequestBodySize(size RequestBodySize) {
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	scope.requestBodySize = size
+}
+
+// RequestBodySize is the maximum size of request bodies sent to Sentry.
+type RequestBodySize string
+
+// Sizes of request bodies sent to Sentry.
+const (
+	// RequestBodySizeNever never sends request bodies.
+	RequestBodySizeNever RequestBodySize = "never"
+	// RequestBodySizeSmall sends request bodies up to 1000 bytes.
+	RequestBodySizeSmall RequestBodySize = "small"
+	// RequestBodySizeMedium sends request bodies up to 10 KB.
+	RequestBodySizeMedium RequestBodySize = "medium"
+	// RequestBodySizeAlways sends request bodies of any size. Note that
+	// bodies are buffered in memory and that large bodies are dropped if the
+	// event exceeds the MaxEventBytes option.
+	RequestBodySizeAlways RequestBodySize = "always"
+)
+
+// maxBytes returns the maximum number of bytes of the request body to buffer,
+// and false if the body should not be buffered at all.
+func (s RequestBodySize) maxBytes() (int, bool) {
+	switch s {
+	case RequestBodySizeNever:
+		return 0, false
+	case RequestBodySizeSmall:
+		return 1000, true
+	case RequestBodySizeAlways:
+		return int(^uint(0) >> 1), true
+	default:
+		return maxRequestBodyBytes, true
+	}
+}
+
 // maxRequestBodyBytes is the default maximum request body size to send to
 // Sentry.
 const maxRequestBodyBytes = 10 * 1024
 
+// requestData returns the request body to report in Request.Data. JSON and
+// form-encoded bodies are decoded into structured data, such that Sentry can
+// scrub sensitive fields. Other bodies, and bodies that cannot be decoded,
+// are returned as a string.
+func requestData(contentType string, body []byte) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err == nil {
+			return v
+		}
+	case mediaType == "application/x-www-form-urlencoded":
+		// Bodies without any value, such as a plain "payload", are not really
+		// form-encoded and are kept as is.
+		values, err := url.ParseQuery(string(body))
+		if err != nil || !hasFormValue(values) {
+			break
+		}
+		form := make(map[string]interface{}, len(values))
+		for k, v := range values {
+			if len(v) == 1 {
+				form[k] = v[0]
+			} else {
+				form[k] = v
+			}
+		}
+		return form
+	}
+	return string(body)
+}
+
+func hasFormValue(values url.Values) bool {
+	for _, v := range values {
+		for _, s := range v {
+			if s != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // A limitedBuffer is like a bytes.Buffer, but limited to store at most Capacity
 // bytes. Any writes past the capacity are silently discarded, similar to
 // ioutil.Discard.
@@ -184,6 +290,23 @@ type readCloser struct {
 	io.Closer
 }
 
+// AddAttachment adds an attachment to the current scope. The attachment is
+// sent with every event captured in the scope.
+func (scope *Scope) AddAttachment(attachment Attachment) {
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	scope.attachments = append(scope.attachments, &attachment)
+}
+
+// ClearAttachments removes all attachments from the current scope.
+func (scope *Scope) ClearAttachments() {
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	scope.attachments = nil
+}
+
 // SetTag adds a tag to the current scope.
 func (scope *Scope) SetTag(key, value string) {
 	scope.mu.Lock()
@@ -228,6 +351,38 @@ func (scope *Scope) SetContexts(contexts map[string]interface{}) {
 	}
 }
 
+// SetOSContext sets the operating system context of the current scope.
+func (scope *Scope) SetOSContext(context OSContext) {
+	scope.SetContext(OSContextKey, context)
+}
+
+// SetRuntimeContext sets the runtime context of the current scope.
+func (scope *Scope) SetRuntimeContext(context RuntimeContext) {
+	scope.SetContext(RuntimeContextKey, context)
+}
+
+// SetDeviceContext sets the device context of the current scope.
+func (scope *Scope) SetDeviceContext(context DeviceContext) {
+	scope.SetContext(DeviceContextKey, context)
+}
+
+// SetAppContext sets the application context of the current scope.
+func (scope *Scope) SetAppContext(context AppContext) {
+	scope.SetContext(AppContextKey, context)
+}
+
+// SetCultureContext sets the culture context of the current scope.
+func (scope *Scope) SetCultureContext(context CultureContext) {
+	scope.SetContext(CultureContextKey, context)
+}
+
+// SetTraceContext sets the trace context of the current scope.
+//
+// Experimental: This is part of a beta feature of the SDK.
+func (scope *Scope) SetTraceContext(context TraceContext) {
+	scope.SetContext(TraceContextKey, context)
+}
+
 // RemoveContext removes a context from the current scope.
 func (scope *Scope) RemoveContext(key string) {
 	scope.mu.Lock()
@@ -310,6 +465,8 @@ func (scope *Scope) Clone() *Scope {
 	clone.transaction = scope.transaction
 	clone.request = scope.request
 	clone.requestBody = scope.requestBody
+	clone.requestBodySize = scope.requestBodySize
+	clone.attachments = append([]*Attachment(nil), scope.attachments...)
 
 	return clone
 }
@@ -400,10 +557,14 @@ func (scope *Scope) ApplyToEvent(event *Event, hint *EventHint) *Event {
 		// Users can still send more data along their events if they want to,
 		// for example using Event.Extra.
 		if scope.requestBody != nil && !scope.requestBody.Overflow() {
-			event.Request.Data = string(scope.requestBody.Bytes())
+			event.Request.Data = requestData(scope.request.Header.Get("Content-Type"), scope.requestBody.Bytes())
 		}
 	}
 
+	if len(scope.attachments) > 0 {
+		event.Attachments = append(event.Attachments, scope.attachments...)
+	}
+
 	for _, processor := range scope.eventProcessors {
 		id := event.EventID
 		event = processor(event, hint)
@@ -1,8 +1,10 @@
 package sentry
 
 import (
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -67,6 +69,69 @@ func TestScopeSetRequestOverrides(t *testing.T) {
 	assertEqual(t, r2, scope.request)
 }
 
+func TestScopeRequestData(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		want        interface{}
+	}{
+		{"", "", nil},
+		{"text/plain", "hello", "hello"},
+		{"application/json", `{"a": [1, "b"]}`, map[string]interface{}{"a": []interface{}{1.0, "b"}}},
+		{"application/vnd.api+json; charset=utf-8", `"s"`, "s"},
+		{"application/json", `{"a":`, `{"a":`},
+		{"application/x-www-form-urlencoded", "a=1&b=2&b=3", map[string]interface{}{"a": "1", "b": []string{"2", "3"}}},
+		{"application/x-www-form-urlencoded", "payload", "payload"},
+		{"application/x-www-form-urlencoded", "a=&b=1", map[string]interface{}{"a": "", "b": "1"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.contentType, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/foo", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			scope := NewScope()
+			scope.SetRequest(r)
+			if _, err := ioutil.ReadAll(r.Body); err != nil {
+				t.Fatal(err)
+			}
+			event := scope.ApplyToEvent(NewEvent(), nil)
+			assertEqual(t, event.Request.Data, tt.want)
+		})
+	}
+}
+
+func TestScopeRequestBodySize(t *testing.T) {
+	body := strings.Repeat("x", 2000)
+	tests := []struct {
+		size RequestBodySize
+		want interface{}
+	}{
+		{RequestBodySizeNever, nil},
+		{RequestBodySizeSmall, nil},
+		{RequestBodySizeMedium, body},
+		{"", body},
+		{RequestBodySizeAlways, body},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(string(tt.size), func(t *testing.T) {
+			hub := NewHub(&Client{options: ClientOptions{MaxRequestBodySize: tt.size}}, NewScope())
+			scope := hub.Clone().Scope()
+			r := httptest.NewRequest("POST", "/foo", strings.NewReader(body))
+			scope.SetRequest(r)
+			if _, err := ioutil.ReadAll(r.Body); err != nil {
+				t.Fatal(err)
+			}
+			event := scope.ApplyToEvent(NewEvent(), nil)
+			assertEqual(t, event.Request.Data, tt.want)
+
+			scope.SetRequestBody([]byte(body))
+			event = scope.ApplyToEvent(NewEvent(), nil)
+			assertEqual(t, event.Request.Data, tt.want)
+		})
+	}
+}
+
 func TestScopeSetTag(t *testing.T) {
 	scope := NewScope()
 	scope.SetTag("a", "foo")
@@ -625,3 +690,20 @@ func TestEventProcessorsAddEventProcessor(t *testing.T) {
 		t.Error("event should be dropped")
 	}
 }
+
+func TestScopeAddAttachment(t *testing.T) {
+	scope := NewScope()
+	scope.AddAttachment(Attachment{Filename: "a.txt", Payload: []byte("a")})
+	clone := scope.Clone()
+	clone.AddAttachment(Attachment{Filename: "b.txt", Payload: []byte("b")})
+
+	event := scope.ApplyToEvent(NewEvent(), nil)
+	assertEqual(t, event.Attachments, []*Attachment{{Filename: "a.txt", Payload: []byte("a")}})
+
+	event = clone.ApplyToEvent(NewEvent(), nil)
+	assertEqual(t, len(event.Attachments), 2)
+
+	clone.ClearAttachments()
+	event = clone.ApplyToEvent(NewEvent(), nil)
+	assertEqual(t, len(event.Attachments), 0)
+}
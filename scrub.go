@@ -0,0 +1,285 @@
+package sentry
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+)
+
+// scrubbedValue replaces the data redacted by scrub rules.
+const scrubbedValue = "[Filtered]"
+
+// ScrubbingContextKey is the key of the context listing the names of the scrub
+// rules that redacted data from an event.
+const ScrubbingContextKey = "scrubbing"
+
+// A ScrubRule redacts sensitive data from events before they are sent. See the
+// ScrubData and ScrubRules client options.
+type ScrubRule struct {
+	// Name identifies the rule in the scrubbing context of events.
+	Name string
+	// Value matches the sensitive parts of strings, which are replaced with
+	// "[Filtered]".
+	Value *regexp.Regexp
+	// Key matches the keys of sensitive fields, whose values are replaced
+	// with "[Filtered]". Keys are matched in the extra data, contexts, tags,
+	// request headers and data, and breadcrumb data.
+	Key *regexp.Regexp
+
+	// valid, if set, reports whether a match of Value is actually sensitive
+	// data, for instance whether digits pass the checksum of card numbers.
+	valid func(match string) bool
+}
+
+// DefaultScrubRules returns the built-in scrub rules. They redact credit card
+// numbers that pass the Luhn checksum, email addresses, IBANs, JSON Web Tokens
+// and the values of fields named like password, secret or token.
+func DefaultScrubRules() []ScrubRule {
+	// IBANs are matched before credit card numbers, which they may contain.
+	return []ScrubRule{
+		{
+			Name:  "@iban",
+			Value: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`),
+		},
+		{
+			Name:  "@creditcard",
+			Value: regexp.MustCompile(`\b(?:\d{4}[ -]?){3}\d{1,7}\b`),
+			// Timestamps and identifiers are long digit runs too.
+			valid: luhnValid,
+		},
+		{
+			Name:  "@email",
+			Value: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+		},
+		{
+			Name:  "@jwt",
+			Value: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+		},
+		{
+			Name: "@password",
+			Key:  regexp.MustCompile(`(?i)passw(or)?d|secret|token|api_?key|credentials`),
+		},
+	}
+}
+
+// ================================
+// Data Scrubbing Integration
+// ================================
+
+type dataScrubbingIntegration struct {
+	rules []ScrubRule
+}
+
+func (dsi *dataScrubbingIntegration) Name() string {
+	return "DataScrubbing"
+}
+
+func (dsi *dataScrubbingIntegration) SetupOnce(client *Client) {
+	if !client.Options().ScrubData {
+		return
+	}
+	dsi.rules = append(DefaultScrubRules(), client.Options().ScrubRules...)
+	client.addFinalEventProcessor(dsi.processor, dropReasonEventProcessor)
+}
+
+func (dsi *dataScrubbingIntegration) processor(event *Event, hint *EventHint) *Event {
+	s := scrubber{rules: dsi.rules, fired: make(map[string]bool)}
+	s.scrubEvent(event)
+
+	if len(s.fired) == 0 {
+		return event
+	}
+	names := make([]string, 0, len(s.fired))
+	for name := range s.fired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	contexts := make(map[string]interface{}, len(event.Contexts)+1)
+	for k, v := range event.Contexts {
+		contexts[k] = v
+	}
+	contexts[ScrubbingContextKey] = map[string]interface{}{"rules": names}
+	event.Contexts = contexts
+	return event
+}
+
+// A scrubber redacts the data of an event, copying the maps, slices and
+// breadcrumbs it changes.
+type scrubber struct {
+	rules []ScrubRule
+	// fired holds the names of the rules that redacted data.
+	fired map[string]bool
+}
+
+func (s *scrubber) scrubEvent(event *Event) {
+	event.Message = s.scrubString(event.Message)
+	if event.LogEntry != nil {
+		logEntry := *event.LogEntry
+		logEntry.Message = s.scrubString(logEntry.Message)
+		logEntry.Formatted = s.scrubString(logEntry.Formatted)
+		logEntry.Params, _ = s.scrubValue(logEntry.Params).([]interface{})
+		event.LogEntry = &logEntry
+	}
+
+	for i := range event.Exception {
+		event.Exception[i].Value = s.scrubString(event.Exception[i].Value)
+	}
+
+	if event.Breadcrumbs != nil {
+		breadcrumbs := make([]*Breadcrumb, len(event.Breadcrumbs))
+		for i, b := range event.Breadcrumbs {
+			breadcrumb := *b
+			breadcrumb.Message = s.scrubString(breadcrumb.Message)
+			breadcrumb.Data = s.scrubMap(breadcrumb.Data)
+			breadcrumbs[i] = &breadcrumb
+		}
+		event.Breadcrumbs = breadcrumbs
+	}
+
+	event.Extra = s.scrubMap(event.Extra)
+	event.Contexts = s.scrubMap(event.Contexts)
+	event.Tags = s.scrubStringMap(event.Tags)
+
+	if event.Request != nil {
+		request := *event.Request
+		request.URL = s.scrubString(request.URL)
+		request.QueryString = s.scrubString(request.QueryString)
+		request.Cookies = s.scrubString(request.Cookies)
+		request.Data = s.scrubValue(request.Data)
+		request.Headers = s.scrubStringMap(request.Headers)
+		event.Request = &request
+	}
+}
+
+// scrubString redacts the parts of str matched by value rules.
+func (s *scrubber) scrubString(str string) string {
+	if str == "" {
+		return str
+	}
+	for _, rule := range s.rules {
+		if rule.Value == nil || !rule.Value.MatchString(str) {
+			continue
+		}
+		if rule.valid == nil {
+			str = rule.Value.ReplaceAllLiteralString(str, scrubbedValue)
+			s.fired[rule.Name] = true
+			continue
+		}
+		str = rule.Value.ReplaceAllStringFunc(str, func(match string) string {
+			if !rule.valid(match) {
+				return match
+			}
+			s.fired[rule.Name] = true
+			return scrubbedValue
+		})
+	}
+	return str
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum used by
+// credit card numbers. Other characters are ignored.
+func luhnValid(s string) bool {
+	var sum, n int
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
+
+// scrubKey reports whether the value of the field named key must be redacted
+// entirely.
+func (s *scrubber) scrubKey(key string) bool {
+	for _, rule := range s.rules {
+		if rule.Key != nil && rule.Key.MatchString(key) {
+			s.fired[rule.Name] = true
+			return true
+		}
+	}
+	return false
+}
+
+// scrubValue returns a redacted copy of v. Strings, maps and slices are
+// walked recursively. Values of other types, such as structs, are converted to
+// their JSON representation first, as they are sent, except numbers and
+// booleans, which are returned as is.
+func (s *scrubber) scrubValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64, json.Number:
+		return v
+	case string:
+		return s.scrubString(v)
+	case map[string]interface{}:
+		return s.scrubMap(v)
+	case map[string]string:
+		return s.scrubStringMap(v)
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+		values := make([]interface{}, len(v))
+		for i, value := range v {
+			values[i] = s.scrubValue(value)
+		}
+		return values
+	case []string:
+		if v == nil {
+			return v
+		}
+		values := make([]string, len(v))
+		for i, value := range v {
+			values[i] = s.scrubString(value)
+		}
+		return values
+	default:
+		decoded, ok := jsonValue(v)
+		if !ok {
+			// Values that cannot be encoded are left for the transport to
+			// deal with.
+			return v
+		}
+		return s.scrubValue(decoded)
+	}
+}
+
+func (s *scrubber) scrubMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	scrubbed := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if s.scrubKey(k) {
+			scrubbed[k] = scrubbedValue
+			continue
+		}
+		scrubbed[k] = s.scrubValue(v)
+	}
+	return scrubbed
+}
+
+func (s *scrubber) scrubStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	scrubbed := make(map[string]string, len(m))
+	for k, v := range m {
+		if s.scrubKey(k) {
+			scrubbed[k] = scrubbedValue
+			continue
+		}
+		scrubbed[k] = s.scrubString(v)
+	}
+	return scrubbed
+}
@@ -39,6 +39,14 @@ func CaptureMessage(message string) *EventID {
 	return hub.CaptureMessage(message)
 }
 
+// CaptureMessagef captures a message formatted according to a format
+// specifier, as with fmt.Sprintf. Events are grouped by the format string
+// instead of by the formatted message.
+func CaptureMessagef(format string, args ...interface{}) *EventID {
+	hub := CurrentHub()
+	return hub.CaptureMessagef(format, args...)
+}
+
 // CaptureException captures an error.
 func CaptureException(exception error) *EventID {
 	hub := CurrentHub()
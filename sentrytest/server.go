@@ -0,0 +1,424 @@
+// Package sentrytest provides an in-process fake Sentry server for testing
+// code that sends events to Sentry.
+//
+// The server accepts requests to the store and envelope endpoints, validates
+// the X-Sentry-Auth header and decodes payloads back into sentry.Event values.
+// Responses can be scripted to exercise rate limiting, server errors and slow
+// replies without network access:
+//
+//	srv := sentrytest.NewServer()
+//	defer srv.Close()
+//
+//	srv.Enqueue(sentrytest.Response{StatusCode: 429, RetryAfter: "60"})
+//
+//	client, _ := sentry.NewClient(sentry.ClientOptions{Dsn: srv.DSN()})
+package sentrytest
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// defaultPublicKey and defaultProjectID are used to build the DSN of a Server
+// when not configured otherwise.
+const (
+	defaultPublicKey = "public"
+	defaultProjectID = 1
+)
+
+// Response describes a scripted reply of a Server.
+type Response struct {
+	// StatusCode is the HTTP status code of the response. Defaults to 200.
+	StatusCode int
+	// RetryAfter, if not empty, is sent as the value of the Retry-After
+	// header.
+	RetryAfter string
+	// Header holds additional headers to be sent with the response.
+	Header http.Header
+	// Body is the response body. Defaults to a JSON object with the ID of the
+	// received event.
+	Body string
+	// Delay is the amount of time to wait before responding.
+	Delay time.Duration
+}
+
+// A Request is a request received by a Server.
+type Request struct {
+	// Endpoint is either "store" or "envelope".
+	Endpoint string
+	// ProjectID is the project ID extracted from the request URL.
+	ProjectID int
+	// Header holds the request headers.
+	Header http.Header
+	// Auth holds the parsed key-value pairs of the X-Sentry-Auth header.
+	Auth map[string]string
+	// Proxied is true if the request was sent to the server acting as an HTTP
+	// proxy, that is, using an absolute URL in the request line.
+	Proxied bool
+	// Body is the raw request body.
+	Body []byte
+	// Events holds the events and transactions decoded from the body.
+	Events []*sentry.Event
+	// StatusCode is the HTTP status code the server responded with. It is
+	// zero if no response was sent, for instance because the client gave up
+	// waiting for a delayed response.
+	StatusCode int
+	// Err is the reason why the request was rejected, if any.
+	Err error
+}
+
+// Options configure a Server.
+type Options struct {
+	// PublicKey is the key expected in the X-Sentry-Auth header. Defaults to
+	// "public".
+	PublicKey string
+	// SecretKey, if not empty, is expected in the X-Sentry-Auth header.
+	SecretKey string
+	// ProjectID is the ID of the project the server accepts events for.
+	// Defaults to 1.
+	ProjectID int
+	// TLS configures the server to use HTTPS with a self-signed certificate.
+	TLS bool
+}
+
+// Server is a fake Sentry server built on top of httptest.Server.
+type Server struct {
+	*httptest.Server
+
+	options Options
+
+	mu        sync.Mutex
+	responses []Response
+	requests  []*Request
+	notify    chan struct{}
+}
+
+// NewServer starts and returns a new Server using the default Options. The
+// caller should call Close when finished, to shut it down.
+func NewServer() *Server {
+	return NewServerWithOptions(Options{})
+}
+
+// NewServerWithOptions starts and returns a new Server configured with
+// options. The caller should call Close when finished, to shut it down.
+func NewServerWithOptions(options Options) *Server {
+	if options.PublicKey == "" {
+		options.PublicKey = defaultPublicKey
+	}
+	if options.ProjectID == 0 {
+		options.ProjectID = defaultProjectID
+	}
+	s := &Server{
+		options: options,
+		notify:  make(chan struct{}, 1),
+	}
+	if options.TLS {
+		s.Server = httptest.NewTLSServer(http.HandlerFunc(s.handle))
+	} else {
+		s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	}
+	return s
+}
+
+// DSN returns a DSN that points to the server.
+func (s *Server) DSN() string {
+	scheme := "http"
+	if s.TLS != nil {
+		scheme = "https"
+	}
+	userinfo := s.options.PublicKey
+	if s.options.SecretKey != "" {
+		userinfo += ":" + s.options.SecretKey
+	}
+	return fmt.Sprintf("%s://%s@%s/%d", scheme, userinfo, s.Listener.Addr(), s.options.ProjectID)
+}
+
+// CertPool returns a certificate pool that trusts the server certificate. It
+// returns nil if the server does not use TLS. It is meant to be used as the
+// CaCerts client option.
+func (s *Server) CertPool() *x509.CertPool {
+	if s.TLS == nil {
+		return nil
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(s.Certificate())
+	return pool
+}
+
+// Enqueue appends responses to the queue of scripted replies. Each request
+// consumes one response from the queue. When the queue is empty, the server
+// responds with 200 OK.
+func (s *Server) Enqueue(responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = append(s.responses, responses...)
+}
+
+// Requests returns all requests received so far.
+func (s *Server) Requests() []*Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := make([]*Request, len(s.requests))
+	copy(requests, s.requests)
+	return requests
+}
+
+// Events returns all events from requests the server responded to with a 2xx
+// status code.
+func (s *Server) Events() []*sentry.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []*sentry.Event
+	for _, r := range s.requests {
+		if r.Err == nil && r.StatusCode >= 200 && r.StatusCode < 300 {
+			events = append(events, r.Events...)
+		}
+	}
+	return events
+}
+
+// WaitForEvents blocks until at least n events were received or the timeout
+// is reached. It returns the events received so far.
+func (s *Server) WaitForEvents(n int, timeout time.Duration) []*sentry.Event {
+	deadline := time.After(timeout)
+	for {
+		events := s.Events()
+		if len(events) >= n {
+			return events
+		}
+		select {
+		case <-s.notify:
+		case <-deadline:
+			return s.Events()
+		}
+	}
+}
+
+var apiPath = regexp.MustCompile(`/api/(\d+)/(store|envelope)/$`)
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	req := &Request{
+		Header:  r.Header,
+		Proxied: r.URL.IsAbs(),
+	}
+	defer s.record(req)
+
+	m := apiPath.FindStringSubmatch(r.URL.Path)
+	if m == nil {
+		req.Err = fmt.Errorf("unexpected path %q", r.URL.Path)
+		req.StatusCode = http.StatusNotFound
+		http.NotFound(w, r)
+		return
+	}
+	req.ProjectID, _ = strconv.Atoi(m[1])
+	req.Endpoint = m[2]
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		req.Err = err
+		req.StatusCode = http.StatusBadRequest
+		http.Error(w, err.Error(), req.StatusCode)
+		return
+	}
+	req.Body = body
+
+	if err := s.validate(r, req); err != nil {
+		req.Err = err
+		req.StatusCode = http.StatusUnauthorized
+		http.Error(w, err.Error(), req.StatusCode)
+		return
+	}
+
+	switch req.Endpoint {
+	case "store":
+		var event sentry.Event
+		err = json.Unmarshal(body, &event)
+		req.Events = []*sentry.Event{&event}
+	case "envelope":
+		req.Events, err = decodeEnvelope(body)
+	}
+	if err != nil {
+		req.Err = err
+		req.StatusCode = http.StatusBadRequest
+		http.Error(w, err.Error(), req.StatusCode)
+		return
+	}
+
+	res := s.nextResponse()
+	if res.Delay > 0 {
+		select {
+		case <-time.After(res.Delay):
+		case <-r.Context().Done():
+			req.Err = r.Context().Err()
+			return
+		}
+	}
+	for k, v := range res.Header {
+		w.Header()[k] = v
+	}
+	if res.RetryAfter != "" {
+		w.Header().Set("Retry-After", res.RetryAfter)
+	}
+	if res.StatusCode == 0 {
+		res.StatusCode = http.StatusOK
+	}
+	if res.Body == "" && res.StatusCode == http.StatusOK {
+		var id sentry.EventID
+		if len(req.Events) > 0 {
+			id = req.Events[0].EventID
+		}
+		res.Body = fmt.Sprintf(`{"id":%q}`, id)
+	}
+	req.StatusCode = res.StatusCode
+	w.WriteHeader(res.StatusCode)
+	_, _ = w.Write([]byte(res.Body))
+}
+
+func (s *Server) record(req *Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Server) nextResponse() Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.responses) == 0 {
+		return Response{}
+	}
+	res := s.responses[0]
+	s.responses = s.responses[1:]
+	return res
+}
+
+// validate checks the request against what is expected from the SDK, in
+// particular the X-Sentry-Auth header as built by Dsn.RequestHeaders.
+func (s *Server) validate(r *http.Request, req *Request) error {
+	if r.Method != http.MethodPost {
+		return fmt.Errorf("unexpected method %s", r.Method)
+	}
+	if req.ProjectID != s.options.ProjectID {
+		return fmt.Errorf("unexpected project ID %d", req.ProjectID)
+	}
+	auth, err := parseAuthHeader(r.Header.Get("X-Sentry-Auth"))
+	if err != nil {
+		return err
+	}
+	req.Auth = auth
+	if auth["sentry_key"] != s.options.PublicKey {
+		return fmt.Errorf("invalid sentry_key %q", auth["sentry_key"])
+	}
+	if auth["sentry_secret"] != s.options.SecretKey {
+		return fmt.Errorf("invalid sentry_secret %q", auth["sentry_secret"])
+	}
+	if auth["sentry_version"] == "" {
+		return fmt.Errorf("missing sentry_version")
+	}
+	if !strings.HasPrefix(auth["sentry_client"], "sentry.go/") {
+		return fmt.Errorf("invalid sentry_client %q", auth["sentry_client"])
+	}
+	return nil
+}
+
+// parseAuthHeader parses the value of a X-Sentry-Auth header into a map of
+// key-value pairs.
+func parseAuthHeader(s string) (map[string]string, error) {
+	const prefix = "Sentry "
+	if !strings.HasPrefix(s, prefix) {
+		return nil, fmt.Errorf("invalid X-Sentry-Auth header %q", s)
+	}
+	auth := make(map[string]string)
+	for _, pair := range strings.Split(s[len(prefix):], ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid X-Sentry-Auth header %q", s)
+		}
+		auth[kv[0]] = kv[1]
+	}
+	return auth, nil
+}
+
+// decodeEnvelope decodes the event and transaction items of an envelope.
+// Items of other types are ignored.
+func decodeEnvelope(b []byte) ([]*sentry.Event, error) {
+	r := bufio.NewReader(bytes.NewReader(b))
+
+	// Envelope header.
+	if _, err := readLine(r); err != nil {
+		return nil, fmt.Errorf("envelope header: %w", err)
+	}
+
+	var events []*sentry.Event
+	for {
+		line, err := readLine(r)
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("item header: %w", err)
+		}
+		if len(line) == 0 {
+			continue
+		}
+		var header struct {
+			Type   string `json:"type"`
+			Length *int   `json:"length"`
+		}
+		if err := json.Unmarshal(line, &header); err != nil {
+			return nil, fmt.Errorf("item header: %w", err)
+		}
+		var payload []byte
+		if header.Length != nil {
+			payload = make([]byte, *header.Length)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return nil, fmt.Errorf("item payload: %w", err)
+			}
+			// Consume the optional newline after the payload.
+			if c, err := r.ReadByte(); err == nil && c != '\n' {
+				_ = r.UnreadByte()
+			}
+		} else {
+			payload, err = readLine(r)
+			if err != nil && err != io.EOF {
+				return nil, fmt.Errorf("item payload: %w", err)
+			}
+		}
+		switch header.Type {
+		case "event", "transaction":
+			var event sentry.Event
+			if err := json.Unmarshal(payload, &event); err != nil {
+				return nil, fmt.Errorf("item payload: %w", err)
+			}
+			events = append(events, &event)
+		}
+	}
+}
+
+// readLine reads a line without the trailing newline. The last line of the
+// input is not required to end with a newline. It returns io.EOF only if there
+// is no more input.
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadBytes('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	return bytes.TrimSuffix(line, []byte{'\n'}), err
+}
@@ -0,0 +1,196 @@
+package sentrytest_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/getsentry/sentry-go/sentrytest"
+)
+
+func newClient(t *testing.T, options sentry.ClientOptions) *sentry.Client {
+	t.Helper()
+	if options.Transport == nil {
+		options.Transport = sentry.NewHTTPSyncTransport()
+	}
+	client, err := sentry.NewClient(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestServerStore(t *testing.T) {
+	srv := sentrytest.NewServer()
+	defer srv.Close()
+
+	client := newClient(t, sentry.ClientOptions{Dsn: srv.DSN()})
+	client.CaptureMessage("hello", nil, nil)
+
+	events := srv.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if events[0].Message != "hello" {
+		t.Errorf("Message = %q, want %q", events[0].Message, "hello")
+	}
+	req := srv.Requests()[0]
+	if req.Endpoint != "store" {
+		t.Errorf("Endpoint = %q, want %q", req.Endpoint, "store")
+	}
+	if req.Auth["sentry_key"] != "public" {
+		t.Errorf("sentry_key = %q, want %q", req.Auth["sentry_key"], "public")
+	}
+}
+
+func TestServerEnvelope(t *testing.T) {
+	srv := sentrytest.NewServer()
+	defer srv.Close()
+
+	client := newClient(t, sentry.ClientOptions{Dsn: srv.DSN()})
+	event := sentry.NewEvent()
+	event.Type = "transaction"
+	event.Transaction = "GET /"
+	event.StartTimestamp = time.Now().Add(-time.Second)
+	client.CaptureEvent(event, nil, nil)
+
+	events := srv.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if events[0].Transaction != "GET /" {
+		t.Errorf("Transaction = %q, want %q", events[0].Transaction, "GET /")
+	}
+	if req := srv.Requests()[0]; req.Endpoint != "envelope" {
+		t.Errorf("Endpoint = %q, want %q", req.Endpoint, "envelope")
+	}
+}
+
+func TestServerRejectsInvalidAuth(t *testing.T) {
+	srv := sentrytest.NewServerWithOptions(sentrytest.Options{
+		PublicKey: "right",
+	})
+	defer srv.Close()
+
+	dsn := strings.Replace(srv.DSN(), "right@", "wrong@", 1)
+	client := newClient(t, sentry.ClientOptions{Dsn: dsn})
+	client.CaptureMessage("hello", nil, nil)
+
+	if events := srv.Events(); len(events) != 0 {
+		t.Fatalf("got %d events, want 0", len(events))
+	}
+	req := srv.Requests()[0]
+	if req.StatusCode != http.StatusUnauthorized {
+		t.Errorf("StatusCode = %d, want %d", req.StatusCode, http.StatusUnauthorized)
+	}
+	if req.Err == nil {
+		t.Error("Err = nil, want non-nil")
+	}
+}
+
+func TestServerRateLimit(t *testing.T) {
+	srv := sentrytest.NewServer()
+	defer srv.Close()
+
+	srv.Enqueue(sentrytest.Response{
+		StatusCode: http.StatusTooManyRequests,
+		RetryAfter: "60",
+	})
+
+	client := newClient(t, sentry.ClientOptions{Dsn: srv.DSN()})
+	client.CaptureMessage("first", nil, nil)
+	client.CaptureMessage("second", nil, nil)
+
+	if n := len(srv.Requests()); n != 1 {
+		t.Fatalf("got %d requests, want 1 (transport should back off)", n)
+	}
+	if events := srv.Events(); len(events) != 0 {
+		t.Fatalf("got %d events, want 0", len(events))
+	}
+}
+
+func TestServerTLS(t *testing.T) {
+	srv := sentrytest.NewServerWithOptions(sentrytest.Options{TLS: true})
+	defer srv.Close()
+
+	client := newClient(t, sentry.ClientOptions{
+		Dsn:     srv.DSN(),
+		CaCerts: srv.CertPool(),
+	})
+	client.CaptureMessage("hello", nil, nil)
+
+	if events := srv.Events(); len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+}
+
+func TestServerTLSUntrusted(t *testing.T) {
+	srv := sentrytest.NewServerWithOptions(sentrytest.Options{TLS: true})
+	defer srv.Close()
+
+	client := newClient(t, sentry.ClientOptions{Dsn: srv.DSN()})
+	client.CaptureMessage("hello", nil, nil)
+
+	if n := len(srv.Requests()); n != 0 {
+		t.Fatalf("got %d requests, want 0", n)
+	}
+}
+
+func TestServerAsProxy(t *testing.T) {
+	srv := sentrytest.NewServer()
+	defer srv.Close()
+
+	// The DSN host does not resolve, all requests must go through the proxy.
+	client := newClient(t, sentry.ClientOptions{
+		Dsn:       "http://public@sentry.invalid/1",
+		HTTPProxy: srv.URL,
+	})
+	client.CaptureMessage("hello", nil, nil)
+
+	if events := srv.Events(); len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if req := srv.Requests()[0]; !req.Proxied {
+		t.Error("Proxied = false, want true")
+	}
+}
+
+func TestServerSlowResponse(t *testing.T) {
+	srv := sentrytest.NewServer()
+	defer srv.Close()
+
+	srv.Enqueue(sentrytest.Response{Delay: time.Second})
+
+	transport := sentry.NewHTTPSyncTransport()
+	transport.Timeout = 50 * time.Millisecond
+	client := newClient(t, sentry.ClientOptions{
+		Dsn:       srv.DSN(),
+		Transport: transport,
+	})
+	client.CaptureMessage("hello", nil, nil)
+
+	if events := srv.WaitForEvents(1, 100*time.Millisecond); len(events) != 0 {
+		t.Fatalf("got %d events, want 0", len(events))
+	}
+}
+
+func TestServerAsyncTransport(t *testing.T) {
+	srv := sentrytest.NewServer()
+	defer srv.Close()
+
+	client := newClient(t, sentry.ClientOptions{
+		Dsn:       srv.DSN(),
+		Transport: sentry.NewHTTPTransport(),
+	})
+	client.CaptureMessage("one", nil, nil)
+	client.CaptureMessage("two", nil, nil)
+	if !client.Flush(time.Second) {
+		t.Fatal("Flush timed out")
+	}
+
+	if events := srv.WaitForEvents(2, time.Second); len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+}
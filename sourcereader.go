@@ -2,38 +2,175 @@ package sentry
 
 import (
 	"bytes"
+	"container/list"
+	"errors"
 	"io/ioutil"
+	"os"
 	"sync"
 )
 
+// A SourceFileReader reads the source files of stack trace frames, to add
+// source code context to the frames. See the SourceFileReader client option.
+type SourceFileReader interface {
+	// ReadSourceFile returns the contents of the source file of the frame.
+	ReadSourceFile(frame Frame) ([]byte, error)
+}
+
+// maxSourceFileBytes is the default size limit of the source files read to
+// add source code context to stack trace frames.
+const maxSourceFileBytes = 1024 * 1024
+
+// maxSourceCacheBytes is the size limit of the cache of source files shared
+// by all clients.
+const maxSourceCacheBytes = 10 * 1024 * 1024
+
+// sharedSourceCache caches the source files read by all clients.
+var sharedSourceCache = newSourceCache(maxSourceCacheBytes)
+
 type sourceReader struct {
-	mu    sync.Mutex
-	cache map[string][][]byte
+	cache *sourceCache
+	// maxFileBytes is the size limit of the files read, 0 for no limit.
+	// Larger files are ignored.
+	maxFileBytes int
 }
 
 func newSourceReader() sourceReader {
 	return sourceReader{
-		cache: make(map[string][][]byte),
+		cache: sharedSourceCache,
 	}
 }
 
+// readContextLines reads the context lines around line from the file on disk.
 func (sr *sourceReader) readContextLines(filename string, line, context int) ([][]byte, int) {
-	sr.mu.Lock()
-	defer sr.mu.Unlock()
+	return sr.readContextLinesFunc(filename, func() ([]byte, error) {
+		if sr.maxFileBytes > 0 {
+			fi, err := os.Stat(filename)
+			if err != nil {
+				return nil, err
+			}
+			if fi.Size() > int64(sr.maxFileBytes) {
+				return nil, sourceFileTooLargeError{size: int(fi.Size())}
+			}
+		}
+		return ioutil.ReadFile(filename)
+	}, line, context)
+}
+
+// readContextLinesFunc reads the context lines around line from the file
+// identified by key. The file is read with read the first time, then from
+// the cache.
+func (sr *sourceReader) readContextLinesFunc(key string, read func() ([]byte, error), line, context int) ([][]byte, int) {
+	entry, ok := sr.cache.get(key)
+	// Clients may have different file size limits.
+	if ok && sr.maxFileBytes > 0 && entry.size > sr.maxFileBytes {
+		return nil, 0
+	}
+	if !ok || (entry.lines == nil && entry.size > 0) {
+		data, err := read()
+		var tooLarge sourceFileTooLargeError
+		switch {
+		case err == nil && sr.maxFileBytes > 0 && len(data) > sr.maxFileBytes:
+			// Remember the size, not the contents, of large files.
+			entry = sourceCacheEntry{size: len(data)}
+		case errors.As(err, &tooLarge):
+			entry = sourceCacheEntry{size: tooLarge.size}
+		case err != nil:
+			entry = sourceCacheEntry{}
+		default:
+			entry = sourceCacheEntry{lines: bytes.Split(data, []byte{'\n'}), size: len(data)}
+		}
+		sr.cache.add(key, entry)
+	}
+
+	return sr.calculateContextLines(entry.lines, line, context)
+}
+
+// sourceFileTooLargeError is returned instead of the contents of files larger
+// than the size limit of a reader. The size is cached such that readers with
+// a larger limit read the file.
+type sourceFileTooLargeError struct {
+	size int
+}
+
+func (e sourceFileTooLargeError) Error() string {
+	return "source file too large"
+}
 
-	lines, ok := sr.cache[filename]
+// A sourceCache is a least recently used cache of source files, bounded by the
+// total size of the files. It is safe for concurrent use.
+type sourceCache struct {
+	mu       sync.Mutex
+	maxBytes int
+	bytes    int
+	// ll holds the entries, most recently used first.
+	ll      *list.List
+	entries map[string]*list.Element
+}
+
+// A sourceCacheEntry holds the lines of a source file and the size of the
+// file. Files that could not be read have no lines. Files too large to be
+// read have no lines but a size.
+type sourceCacheEntry struct {
+	key   string
+	lines [][]byte
+	size  int
+}
+
+// cost returns the memory used by the entry, approximately.
+func (e sourceCacheEntry) cost() int {
+	const sliceHeaderBytes = 24
+	n := len(e.key) + sliceHeaderBytes*(len(e.lines)+1)
+	if e.lines != nil {
+		n += e.size
+	}
+	return n
+}
+
+func newSourceCache(maxBytes int) *sourceCache {
+	return &sourceCache{
+		maxBytes: maxBytes,
+		ll:       list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
 
+func (c *sourceCache) get(key string) (sourceCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
 	if !ok {
-		data, err := ioutil.ReadFile(filename)
-		if err != nil {
-			sr.cache[filename] = nil
-			return nil, 0
-		}
-		lines = bytes.Split(data, []byte{'\n'})
-		sr.cache[filename] = lines
+		return sourceCacheEntry{}, false
 	}
+	c.ll.MoveToFront(elem)
+	return elem.Value.(sourceCacheEntry), true
+}
+
+// add adds the entry to the cache, replacing any entry with the same key, and
+// evicts the least recently used entries until the cache fits its limit.
+// Entries larger than the limit are not added.
+func (c *sourceCache) add(key string, entry sourceCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry.key = key
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	if entry.cost() > c.maxBytes {
+		return
+	}
+	c.entries[key] = c.ll.PushFront(entry)
+	c.bytes += entry.cost()
+	for c.bytes > c.maxBytes {
+		c.remove(c.ll.Back())
+	}
+}
 
-	return sr.calculateContextLines(lines, line, context)
+func (c *sourceCache) remove(elem *list.Element) {
+	entry := c.ll.Remove(elem).(sourceCacheEntry)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.cost()
 }
 
 func (sr *sourceReader) calculateContextLines(lines [][]byte, line, context int) ([][]byte, int) {
@@ -0,0 +1,36 @@
+//go:build go1.16
+// +build go1.16
+
+package sentry
+
+import (
+	"io/fs"
+	"path"
+)
+
+// NewFSSourceFileReader returns a SourceFileReader that reads source files
+// from fsys, for instance an embed.FS compiled into the program or a zip
+// archive opened with archive/zip.
+//
+// Source files of the main module are read at their path relative to the
+// module root, as in Frame.Filename. Source files of other modules are read
+// in a directory named after the module path, for instance
+// "github.com/pkg/errors/errors.go".
+func NewFSSourceFileReader(fsys fs.FS) SourceFileReader {
+	return fsSourceFileReader{fsys: fsys}
+}
+
+type fsSourceFileReader struct {
+	fsys fs.FS
+}
+
+func (r fsSourceFileReader) ReadSourceFile(frame Frame) ([]byte, error) {
+	if frame.Filename == "" || frame.Package == "" {
+		return nil, fs.ErrNotExist
+	}
+	name := frame.Filename
+	if frame.Package != mainModule {
+		name = path.Join(frame.Package, frame.Filename)
+	}
+	return fs.ReadFile(r.fsys, name)
+}
@@ -0,0 +1,67 @@
+//go:build go1.16
+// +build go1.16
+
+package sentry
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestFSSourceFileReader(t *testing.T) {
+	defer func(m string) { mainModule = m }(mainModule)
+	mainModule = "example.com/app"
+
+	r := NewFSSourceFileReader(fstest.MapFS{
+		"db/pool.go":                      {Data: []byte("package db\n")},
+		"github.com/pkg/errors/errors.go": {Data: []byte("package errors\n")},
+	})
+
+	data, err := r.ReadSourceFile(Frame{Package: "example.com/app", Filename: "db/pool.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, string(data), "package db\n")
+
+	data, err = r.ReadSourceFile(Frame{Package: "github.com/pkg/errors", Filename: "errors.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, string(data), "package errors\n")
+
+	if _, err := r.ReadSourceFile(Frame{AbsPath: "/app/db/pool.go"}); err == nil {
+		t.Error("got nil error for frame without package")
+	}
+}
+
+func TestContextifyFramesFS(t *testing.T) {
+	defer func(m string) { mainModule = m }(mainModule)
+	mainModule = "example.com/app"
+
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{
+		Transport:    transport,
+		ContextLines: 1,
+		SourceFileReader: NewFSSourceFileReader(fstest.MapFS{
+			"main.go": {Data: []byte("package main\n\nfunc main() {\n\tpanic(1)\n}\n")},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := NewEvent()
+	event.Exception = []Exception{{Stacktrace: &Stacktrace{Frames: []Frame{{
+		Function: "main",
+		Module:   "main",
+		Package:  "example.com/app",
+		Filename: "main.go",
+		Lineno:   4,
+		InApp:    true,
+	}}}}}
+	client.CaptureEvent(event, nil, nil)
+
+	frame := transport.lastEvent.Exception[0].Stacktrace.Frames[0]
+	assertEqual(t, frame.PreContext, []string{"func main() {"})
+	assertEqual(t, frame.ContextLine, "\tpanic(1)")
+	assertEqual(t, frame.PostContext, []string{"}"})
+}
@@ -1,7 +1,11 @@
 package sentry
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -124,5 +128,117 @@ func TestReadContextLinesNonExistingInput(t *testing.T) {
 	var wantReadLines int
 
 	assertContextLines(t, gotLines, wantLines, gotReadLines, wantReadLines)
-	assertEqual(t, sr.cache["non_existing.go"], wantLines)
+	entry, ok := sr.cache.get("non_existing.go")
+	assertEqual(t, ok, true)
+	assertEqual(t, entry.lines, wantLines)
+}
+
+func TestReadContextLinesMaxFileBytes(t *testing.T) {
+	sr := newSourceReader()
+	sr.cache = newSourceCache(maxSourceCacheBytes)
+	sr.maxFileBytes = 10
+	gotLines, gotReadLines := sr.readContextLines("sourcereader_test.go", 1, 0)
+	var wantLines [][]byte
+	var wantReadLines int
+
+	assertContextLines(t, gotLines, wantLines, gotReadLines, wantReadLines)
+
+	read := func() ([]byte, error) { return []byte("line 1\nline 2 is too long"), nil }
+	gotLines, gotReadLines = sr.readContextLinesFunc("key", read, 1, 0)
+	assertContextLines(t, gotLines, wantLines, gotReadLines, wantReadLines)
+}
+
+func TestSourceCacheEviction(t *testing.T) {
+	entry := func(s string) sourceCacheEntry {
+		return sourceCacheEntry{lines: [][]byte{[]byte(s)}, size: len(s)}
+	}
+	a, b, c := entry("aaaa"), entry("bbbb"), entry("cccc")
+	a.key, b.key, c.key = "a", "b", "c"
+	cache := newSourceCache(a.cost() + b.cost())
+
+	cache.add("a", a)
+	cache.add("b", b)
+	// Use a, such that b is the least recently used entry.
+	if _, ok := cache.get("a"); !ok {
+		t.Fatal("a missing")
+	}
+	cache.add("c", c)
+
+	if _, ok := cache.get("b"); ok {
+		t.Error("b not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.get(key); !ok {
+			t.Errorf("%s evicted", key)
+		}
+	}
+	assertEqual(t, cache.bytes, a.cost()+c.cost())
+
+	// Entries larger than the cache are not added.
+	cache.add("d", entry(strings.Repeat("d", 100)))
+	if _, ok := cache.get("d"); ok {
+		t.Error("oversized entry added")
+	}
+	assertEqual(t, len(cache.entries), 2)
+	assertEqual(t, cache.ll.Len(), 2)
+}
+
+func TestSourceReaderDifferentLimits(t *testing.T) {
+	cache := newSourceCache(maxSourceCacheBytes)
+	read := func() ([]byte, error) { return []byte("line 1\nline 2"), nil }
+
+	small := sourceReader{cache: cache, maxFileBytes: 5}
+	large := sourceReader{cache: cache}
+
+	lines, _ := small.readContextLinesFunc("key", read, 1, 0)
+	assertEqual(t, lines, [][]byte(nil))
+	lines, _ = large.readContextLinesFunc("key", read, 1, 0)
+	assertEqual(t, lines, [][]byte{[]byte("line 1")})
+	lines, _ = small.readContextLinesFunc("key", read, 1, 0)
+	assertEqual(t, lines, [][]byte(nil))
+}
+
+func TestSourceReaderConcurrentReads(t *testing.T) {
+	cache := newSourceCache(200)
+	var reads int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sr := sourceReader{cache: cache}
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("file%d.go", (i+j)%10)
+				lines, contextLine := sr.readContextLinesFunc(key, func() ([]byte, error) {
+					atomic.AddInt32(&reads, 1)
+					return []byte(key + "\n" + key), nil
+				}, 2, 1)
+				if len(lines) != 2 || string(lines[contextLine]) != key {
+					t.Errorf("got %q, %d for %s", lines, contextLine, key)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if cache.bytes > cache.maxBytes {
+		t.Errorf("cache holds %d bytes, want at most %d", cache.bytes, cache.maxBytes)
+	}
+	assertEqual(t, len(cache.entries), cache.ll.Len())
+	if reads == 0 {
+		t.Error("no file read")
+	}
+}
+
+func TestSourceReaderDifferentLimitsDisk(t *testing.T) {
+	cache := newSourceCache(maxSourceCacheBytes)
+	small := sourceReader{cache: cache, maxFileBytes: 100}
+	large := sourceReader{cache: cache, maxFileBytes: maxSourceFileBytes}
+
+	lines, _ := small.readContextLines("sourcereader_test.go", 3, 2)
+	assertEqual(t, lines, [][]byte(nil))
+	lines, _ = large.readContextLines("sourcereader_test.go", 3, 2)
+	assertEqual(t, len(lines), 5)
+	lines, _ = small.readContextLines("sourcereader_test.go", 3, 2)
+	assertEqual(t, lines, [][]byte(nil))
 }
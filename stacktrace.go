@@ -2,10 +2,14 @@ package sentry
 
 import (
 	"go/build"
+	"path"
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"runtime/debug"
+	"sort"
 	"strings"
+	"sync"
 )
 
 const unknown string = "unknown"
@@ -23,8 +27,16 @@ type Stacktrace struct {
 	FramesOmitted []uint  `json:"frames_omitted,omitempty"`
 }
 
-// NewStacktrace creates a stacktrace using runtime.Callers.
+// NewStacktrace creates a stacktrace using runtime.Callers. Frames are
+// filtered with DefaultFrameFilter.
 func NewStacktrace() *Stacktrace {
+	return newStacktrace(DefaultFrameFilter)
+}
+
+// newStacktrace creates a stack trace using runtime.Callers, keeping the
+// frames for which keep returns true. The innermost frames of the SDK, those
+// of the functions capturing the stack trace, are always removed.
+func newStacktrace(keep func(Frame) bool) *Stacktrace {
 	pcs := make([]uintptr, 100)
 	n := runtime.Callers(1, pcs)
 
@@ -33,7 +45,7 @@ func NewStacktrace() *Stacktrace {
 	}
 
 	frames := extractFrames(pcs[:n])
-	frames = filterFrames(frames)
+	frames = selectFrames(dropCaptureFrames(frames), keep)
 
 	stacktrace := Stacktrace{
 		Frames: frames,
@@ -42,28 +54,48 @@ func NewStacktrace() *Stacktrace {
 	return &stacktrace
 }
 
-// TODO: Make it configurable so that anyone can provide their own implementation?
-// Use of reflection allows us to not have a hard dependency on any given
-// package, so we don't have to import it.
+// A StacktraceExtractor returns the program counters of the stack trace
+// stored in an error, as returned by runtime.Callers, or nil if the error
+// doesn't store a stack trace it knows about.
+type StacktraceExtractor func(err error) []uintptr
 
-// ExtractStacktrace creates a new Stacktrace based on the given error.
-func ExtractStacktrace(err error) *Stacktrace {
-	method := extractReflectedStacktraceMethod(err)
+var stacktraceExtractors struct {
+	sync.RWMutex
+	list []StacktraceExtractor
+}
 
-	var pcs []uintptr
+// RegisterStacktraceExtractor registers a function that extracts stack traces
+// from errors, to support error packages that the SDK doesn't know about.
+//
+// Extractors are tried in the reverse order of registration, before the
+// built-in ones, until one returns program counters. The built-in extractors
+// support errors with a Callers() []uintptr method and the errors of
+// github.com/pkg/errors, github.com/pingcap/errors, github.com/go-errors/errors
+// and golang.org/x/xerrors, as well as errors compatible with them, such as
+// the errors of github.com/cockroachdb/errors.
+func RegisterStacktraceExtractor(extractor StacktraceExtractor) {
+	stacktraceExtractors.Lock()
+	defer stacktraceExtractors.Unlock()
+	stacktraceExtractors.list = append(stacktraceExtractors.list, extractor)
+}
 
-	if method.IsValid() {
-		pcs = extractPcs(method)
-	} else {
-		pcs = extractXErrorsPC(err)
-	}
+// ExtractStacktrace creates a new Stacktrace based on the given error. Frames
+// are filtered with DefaultFrameFilter.
+func ExtractStacktrace(err error) *Stacktrace {
+	return extractStacktrace(err, DefaultFrameFilter)
+}
+
+// extractStacktrace creates a stack trace based on the given error, keeping
+// the frames for which keep returns true.
+func extractStacktrace(err error, keep func(Frame) bool) *Stacktrace {
+	pcs := extractPcsFromError(err)
 
 	if len(pcs) == 0 {
 		return nil
 	}
 
 	frames := extractFrames(pcs)
-	frames = filterFrames(frames)
+	frames = selectFrames(dropCaptureFrames(frames), keep)
 
 	stacktrace := Stacktrace{
 		Frames: frames,
@@ -72,6 +104,34 @@ func ExtractStacktrace(err error) *Stacktrace {
 	return &stacktrace
 }
 
+// extractPcsFromError returns the program counters of the stack trace stored
+// in err, using the registered extractors first and then the built-in ones.
+func extractPcsFromError(err error) []uintptr {
+	stacktraceExtractors.RLock()
+	extractors := stacktraceExtractors.list
+	stacktraceExtractors.RUnlock()
+
+	for i := len(extractors) - 1; i >= 0; i-- {
+		if pcs := extractors[i](err); len(pcs) > 0 {
+			return pcs
+		}
+	}
+
+	if err, ok := err.(interface{ Callers() []uintptr }); ok {
+		if pcs := err.Callers(); len(pcs) > 0 {
+			return pcs
+		}
+	}
+
+	// Use of reflection allows us to not have a hard dependency on any given
+	// package, so we don't have to import it.
+	if method := extractReflectedStacktraceMethod(err); method.IsValid() {
+		return extractPcs(method)
+	}
+
+	return extractXErrorsPC(err)
+}
+
 func extractReflectedStacktraceMethod(err error) reflect.Value {
 	var method reflect.Value
 
@@ -190,12 +250,10 @@ func NewFrame(f runtime.Frame) Frame {
 		abspath = ""
 	case filepath.IsAbs(f.File):
 		abspath = f.File
-		// TODO: in the general case, it is not trivial to come up with a
-		// "project relative" path with the data we have in run time.
 		// We shall not use filepath.Base because it creates ambiguous paths and
-		// affects the "Suspect Commits" feature.
-		// For now, leave relpath empty to be omitted when serializing the event
-		// as JSON. Improve this later.
+		// affects the "Suspect Commits" feature. Leave relpath empty to be
+		// omitted when serializing the event as JSON, unless a path relative
+		// to the module root can be derived from the package path below.
 		relpath = ""
 	default:
 		// f.File is a relative path. This may happen when the binary is built
@@ -206,10 +264,18 @@ func NewFrame(f runtime.Frame) Frame {
 	}
 
 	function := f.Function
-	var pkg string
+	var pkg, module string
 
 	if function != "" {
 		pkg, function = splitQualifiedFunctionName(function)
+		var dir string
+		module, dir = modulePath(pkg)
+		// Go requires all files of a package to be in the same directory, so
+		// the path of a file relative to the module root follows from the
+		// path of its package.
+		if module != "" && f.File != "" {
+			relpath = path.Join(dir, path.Base(filepath.ToSlash(f.File)))
+		}
 	}
 
 	frame := Frame{
@@ -217,7 +283,9 @@ func NewFrame(f runtime.Frame) Frame {
 		Filename: relpath,
 		Lineno:   f.Line,
 		Module:   pkg,
+		Package:  module,
 		Function: function,
+		Symbol:   f.Function,
 	}
 
 	frame.InApp = isInAppFrame(frame)
@@ -253,9 +321,39 @@ func extractFrames(pcs []uintptr) []Frame {
 	return frames
 }
 
+// DefaultFrameFilter reports whether a stack trace frame is meant to be
+// reported to Sentry by default, that is unless the FrameFilter client option
+// is set. Frames internal to the SDK or Go, those of the runtime and testing
+// packages, are left out.
+//
+// A custom FrameFilter replaces DefaultFrameFilter, and may call it to only
+// change the decision for some frames. It applies before MaxFrames. The frames
+// of the SDK functions capturing a stack trace are always left out, and stack
+// traces created with NewStacktrace or ExtractStacktrace are always filtered
+// with DefaultFrameFilter.
+func DefaultFrameFilter(frame Frame) bool {
+	// Skip Go internal frames.
+	if frame.Module == "runtime" || frame.Module == "testing" {
+		return false
+	}
+	// Skip Sentry internal frames, except for frames in _test packages (for
+	// testing).
+	return !isSDKFrame(frame)
+}
+
+func isSDKFrame(frame Frame) bool {
+	return strings.HasPrefix(frame.Module, "github.com/getsentry/sentry-go") &&
+		!strings.HasSuffix(frame.Module, "_test")
+}
+
 // filterFrames filters out stack frames that are not meant to be reported to
-// Sentry. Those are frames internal to the SDK or Go.
+// Sentry by default. Those are frames internal to the SDK or Go.
 func filterFrames(frames []Frame) []Frame {
+	return selectFrames(frames, DefaultFrameFilter)
+}
+
+// selectFrames returns the frames for which keep returns true.
+func selectFrames(frames []Frame, keep func(Frame) bool) []Frame {
 	if len(frames) == 0 {
 		return nil
 	}
@@ -263,22 +361,36 @@ func filterFrames(frames []Frame) []Frame {
 	filteredFrames := make([]Frame, 0, len(frames))
 
 	for _, frame := range frames {
-		// Skip Go internal frames.
-		if frame.Module == "runtime" || frame.Module == "testing" {
-			continue
-		}
-		// Skip Sentry internal frames, except for frames in _test packages (for
-		// testing).
-		if strings.HasPrefix(frame.Module, "github.com/getsentry/sentry-go") &&
-			!strings.HasSuffix(frame.Module, "_test") {
-			continue
+		if keep(frame) {
+			filteredFrames = append(filteredFrames, frame)
 		}
-		filteredFrames = append(filteredFrames, frame)
 	}
 
 	return filteredFrames
 }
 
+// dropCaptureFrames removes the innermost frames of the SDK, which are those
+// of the functions capturing the stack trace rather than of the program.
+func dropCaptureFrames(frames []Frame) []Frame {
+	n := len(frames)
+	for n > 0 && isSDKFrame(frames[n-1]) {
+		n--
+	}
+	return frames[:n]
+}
+
+// mainModule is the path of the main module of the program, or the empty
+// string if the program was built without module support.
+var mainModule = func() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		return info.Main.Path
+	}
+	return ""
+}()
+
+// isInAppFrame reports whether the frame belongs to the program rather than to
+// Go or to a dependency. Frames of the main module are in app. When the main
+// module is unknown, frames of packages outside the module cache are in app.
 func isInAppFrame(frame Frame) bool {
 	if strings.HasPrefix(frame.AbsPath, build.Default.GOROOT) ||
 		strings.Contains(frame.Module, "vendor") ||
@@ -286,7 +398,134 @@ func isInAppFrame(frame Frame) bool {
 		return false
 	}
 
-	return true
+	if frame.Module == "main" {
+		return true
+	}
+
+	if mainModule != "" {
+		// Test packages have the "_test" suffix.
+		return hasPathPrefix(strings.TrimSuffix(frame.Module, "_test"), mainModule)
+	}
+
+	return !strings.Contains(filepath.ToSlash(frame.AbsPath), "/pkg/mod/")
+}
+
+// mainPackage is the path of the main package of the program, or the empty
+// string if unknown.
+var mainPackage = func() string {
+	if info, ok := debug.ReadBuildInfo(); ok && hasPathPrefix(info.Path, info.Main.Path) {
+		return info.Path
+	}
+	return ""
+}()
+
+// modulePaths are the paths of the modules of the program, the main module
+// and its dependencies, longest first.
+var modulePaths = func() []string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	var paths []string
+	if info.Main.Path != "" {
+		paths = append(paths, info.Main.Path)
+	}
+	for _, dep := range info.Deps {
+		paths = append(paths, dep.Path)
+	}
+	sort.Slice(paths, func(i, j int) bool { return len(paths[i]) > len(paths[j]) })
+	return paths
+}()
+
+// modulePath returns the path of the module providing the package pkg, and the
+// directory of the package relative to the module root. It returns empty
+// strings if the module is unknown, as for packages of the standard library.
+func modulePath(pkg string) (module, dir string) {
+	if pkg == "main" {
+		pkg = mainPackage
+	}
+	// Test packages have the "_test" suffix.
+	pkg = strings.TrimSuffix(pkg, "_test")
+	if pkg == "" {
+		return "", ""
+	}
+	for _, module := range modulePaths {
+		if hasPathPrefix(pkg, module) {
+			return module, strings.TrimPrefix(strings.TrimPrefix(pkg, module), "/")
+		}
+	}
+	return "", ""
+}
+
+// repositoryDir returns the directory of the module with the given path
+// relative to the root of its repository, as far as it can be derived from
+// the module path. The repositories of modules hosted on github.com,
+// gitlab.com and bitbucket.org are named by the first three elements of the
+// path, and a major version suffix is assumed to be a branch rather than a
+// directory. Other modules are assumed to be at the root of their repository.
+//
+// Frame file names are made relative to the root of the repository, rather
+// than to the root of their module, as needed by suspect commits and code
+// mappings. The ModuleRepositoryDirs client option overrides repositoryDir.
+func repositoryDir(module string) string {
+	elems := strings.Split(module, "/")
+	switch elems[0] {
+	case "github.com", "gitlab.com", "bitbucket.org":
+	default:
+		return ""
+	}
+	if len(elems) <= 3 {
+		return ""
+	}
+	elems = elems[3:]
+	if isMajorVersion(elems[len(elems)-1]) {
+		elems = elems[:len(elems)-1]
+	}
+	return strings.Join(elems, "/")
+}
+
+// isMajorVersion reports whether elem is a major version suffix of a module
+// path, such as "v2".
+func isMajorVersion(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' || elem[1] == '0' {
+		return false
+	}
+	for _, c := range elem[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return elem != "v1"
+}
+
+// hasPathPrefix reports whether the package path p is prefix or a package
+// nested in prefix.
+func hasPathPrefix(p, prefix string) bool {
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+// setInAppFrames sets whether frames are in app from the InAppInclude and
+// InAppExclude options. Frames of modules with a path prefix in include are
+// in app, otherwise frames of modules with a path prefix in exclude are not.
+// Other frames are left untouched.
+func setInAppFrames(frames []Frame, include, exclude []string) {
+	for i := range frames {
+		switch {
+		case hasAnyPathPrefix(frames[i].Module, include):
+			frames[i].InApp = true
+		case hasAnyPathPrefix(frames[i].Module, exclude):
+			frames[i].InApp = false
+		}
+	}
+}
+
+func hasAnyPathPrefix(p string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if hasPathPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 func callerFunctionName() string {
@@ -281,12 +281,23 @@ func compareStacktrace(t *testing.T, got, want *sentry.Stacktrace) {
 		if filepath.Base(frame.AbsPath) != "stacktrace_external_test.go" {
 			t.Errorf(`Frame{Function: %q}.AbsPath = %q, want ".../stacktrace_external_test.go"`, frame.Function, frame.AbsPath)
 		}
+		// Because stacktraceDiff ignores Frame.Filename, Frame.Package and
+		// Frame.Symbol, check them here.
+		if frame.Filename != "stacktrace_external_test.go" {
+			t.Errorf(`Frame{Function: %q}.Filename = %q, want "stacktrace_external_test.go"`, frame.Function, frame.Filename)
+		}
+		if frame.Package != "github.com/getsentry/sentry-go" {
+			t.Errorf(`Frame{Function: %q}.Package = %q, want "github.com/getsentry/sentry-go"`, frame.Function, frame.Package)
+		}
+		if want := frame.Module + "." + frame.Function; frame.Symbol != want {
+			t.Errorf(`Frame{Function: %q}.Symbol = %q, want %q`, frame.Function, frame.Symbol, want)
+		}
 	}
 }
 
 func stacktraceDiff(x, y *sentry.Stacktrace) string {
 	return cmp.Diff(
 		x, y,
-		cmpopts.IgnoreFields(sentry.Frame{}, "AbsPath", "Filename"),
+		cmpopts.IgnoreFields(sentry.Frame{}, "AbsPath", "Filename", "Package", "Symbol"),
 	)
 }
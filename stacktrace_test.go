@@ -2,9 +2,12 @@ package sentry
 
 import (
 	"errors"
+	"go/build"
+	"runtime"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 func NewStacktraceForTest() *Stacktrace {
@@ -169,3 +172,167 @@ func TestExtractXErrorsPC(t *testing.T) {
 		t.Errorf("got %#v, want nil", got)
 	}
 }
+
+type callersError struct{ pcs []uintptr }
+
+func (e *callersError) Error() string      { return "callers error" }
+func (e *callersError) Callers() []uintptr { return e.pcs }
+
+func TestExtractPcsFromErrorCallers(t *testing.T) {
+	pcs := []uintptr{1, 2, 3}
+	assertEqual(t, extractPcsFromError(&callersError{pcs: pcs}), pcs)
+}
+
+type inHouseError struct{ stack []uintptr }
+
+func (e inHouseError) Error() string { return "in-house error" }
+
+func TestRegisterStacktraceExtractor(t *testing.T) {
+	defer func(list []StacktraceExtractor) {
+		stacktraceExtractors.list = list
+	}(stacktraceExtractors.list)
+
+	RegisterStacktraceExtractor(func(err error) []uintptr {
+		if err, ok := err.(inHouseError); ok {
+			return err.stack
+		}
+		return nil
+	})
+
+	assertEqual(t, extractPcsFromError(inHouseError{stack: []uintptr{1, 2}}), []uintptr{1, 2})
+	// Errors not handled by the extractor fall back to the built-in ones.
+	assertEqual(t, extractPcsFromError(&callersError{pcs: []uintptr{3}}), []uintptr{3})
+	if pcs := extractPcsFromError(inHouseError{}); pcs != nil {
+		t.Errorf("got %v, want nil", pcs)
+	}
+}
+
+func TestIsInAppFrame(t *testing.T) {
+	defer func(m string) { mainModule = m }(mainModule)
+	mainModule = "example.com/app"
+
+	tests := []struct {
+		frame Frame
+		want  bool
+	}{
+		{Frame{Module: "main"}, true},
+		{Frame{Module: "example.com/app"}, true},
+		{Frame{Module: "example.com/app/db"}, true},
+		{Frame{Module: "example.com/app_test"}, true},
+		{Frame{Module: "example.com/application"}, false},
+		{Frame{Module: "github.com/pkg/errors"}, false},
+		{Frame{Module: "example.com/app/vendor/github.com/pkg/errors"}, false},
+		{Frame{Module: "net/http", AbsPath: build.Default.GOROOT + "/src/net/http/server.go"}, false},
+	}
+	for _, tt := range tests {
+		if got := isInAppFrame(tt.frame); got != tt.want {
+			t.Errorf("isInAppFrame(%q) = %v, want %v", tt.frame.Module, got, tt.want)
+		}
+	}
+
+	mainModule = ""
+	assertEqual(t, isInAppFrame(Frame{Module: "example.com/app", AbsPath: "/src/app/main.go"}), true)
+	assertEqual(t, isInAppFrame(Frame{Module: "github.com/pkg/errors", AbsPath: "/go/pkg/mod/github.com/pkg/errors@v0.9.1/errors.go"}), false)
+}
+
+func TestSetInAppFrames(t *testing.T) {
+	frames := []Frame{
+		{Module: "example.com/app", InApp: true},
+		{Module: "example.com/app/generated", InApp: true},
+		{Module: "example.com/lib", InApp: false},
+		{Module: "example.com/library", InApp: false},
+		{Module: "example.com/app/generatedfoo", InApp: true},
+		{Module: "github.com/pkg/errors", InApp: false},
+	}
+	setInAppFrames(frames, []string{"example.com/lib"}, []string{"example.com/app/generated", "example.com/lib"})
+
+	assertEqual(t, frames, []Frame{
+		{Module: "example.com/app", InApp: true},
+		{Module: "example.com/app/generated", InApp: false},
+		{Module: "example.com/lib", InApp: true},
+		{Module: "example.com/library", InApp: false},
+		{Module: "example.com/app/generatedfoo", InApp: true},
+		{Module: "github.com/pkg/errors", InApp: false},
+	})
+}
+
+func TestRepositoryDir(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"github.com/org/repo", ""},
+		{"github.com/org/repo/sub", "sub"},
+		{"gitlab.com/org/repo/sub/dir", "sub/dir"},
+		{"github.com/org/repo/v2", ""},
+		{"github.com/org/repo/sub/v3", "sub"},
+		{"github.com/org/repo/v1", "v1"},
+		{"example.com/org/repo/sub", ""},
+	}
+	for _, tt := range tests {
+		assertEqual(t, repositoryDir(tt.in), tt.want, "repositoryDir(%q)", tt.in)
+	}
+}
+
+func TestNewFrameFilename(t *testing.T) {
+	defer func(paths []string, pkg string) {
+		modulePaths, mainPackage = paths, pkg
+	}(modulePaths, mainPackage)
+	modulePaths = []string{"github.com/pkg/errors", "example.com/app"}
+	mainPackage = "example.com/app/cmd/server"
+
+	tests := []struct {
+		in   runtime.Frame
+		want Frame
+	}{
+		{
+			in: runtime.Frame{Function: "example.com/app/db.(*Pool).Get", File: "/home/user/src/app/db/pool.go"},
+			want: Frame{
+				Function: "(*Pool).Get",
+				Symbol:   "example.com/app/db.(*Pool).Get",
+				Module:   "example.com/app/db",
+				Package:  "example.com/app",
+				Filename: "db/pool.go",
+				AbsPath:  "/home/user/src/app/db/pool.go",
+			},
+		},
+		{
+			// Built with -trimpath.
+			in: runtime.Frame{Function: "github.com/pkg/errors.New", File: "github.com/pkg/errors@v0.9.1/errors.go"},
+			want: Frame{
+				Function: "New",
+				Symbol:   "github.com/pkg/errors.New",
+				Module:   "github.com/pkg/errors",
+				Package:  "github.com/pkg/errors",
+				Filename: "errors.go",
+			},
+		},
+		{
+			in: runtime.Frame{Function: "main.main", File: "/home/user/src/app/cmd/server/main.go"},
+			want: Frame{
+				Function: "main",
+				Symbol:   "main.main",
+				Module:   "main",
+				Package:  "example.com/app",
+				Filename: "cmd/server/main.go",
+				AbsPath:  "/home/user/src/app/cmd/server/main.go",
+			},
+		},
+		{
+			// Standard library, built with -trimpath.
+			in: runtime.Frame{Function: "net/http.(*conn).serve", File: "net/http/server.go"},
+			want: Frame{
+				Function: "(*conn).serve",
+				Symbol:   "net/http.(*conn).serve",
+				Module:   "net/http",
+				Filename: "net/http/server.go",
+			},
+		},
+	}
+	for _, tt := range tests {
+		got := NewFrame(tt.in)
+		if diff := cmp.Diff(tt.want, got, cmpopts.IgnoreFields(Frame{}, "InApp")); diff != "" {
+			t.Errorf("NewFrame(%q) mismatch (-want +got):\n%s", tt.in.Function, diff)
+		}
+	}
+}
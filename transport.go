@@ -48,6 +48,26 @@ func getTLSConfig(options ClientOptions) *tls.Config {
 	return nil
 }
 
+// NewHTTPClient returns the HTTP client used by the transports of the SDK to
+// send data to Sentry, with the given request timeout. It follows the
+// HTTPClient, HTTPTransport, HTTPProxy, HTTPSProxy and CaCerts client options.
+func NewHTTPClient(options ClientOptions, timeout time.Duration) *http.Client {
+	if options.HTTPClient != nil {
+		return options.HTTPClient
+	}
+	transport := options.HTTPTransport
+	if transport == nil {
+		transport = &http.Transport{
+			Proxy:           getProxyConfig(options),
+			TLSClientConfig: getTLSConfig(options),
+		}
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
+}
+
 func retryAfter(now time.Time, r *http.Response) time.Duration {
 	retryAfterHeader := r.Header["Retry-After"]
 
@@ -97,6 +117,18 @@ func getRequestBodyFromEvent(event *Event) []byte {
 }
 
 func transactionEnvelopeFromBody(eventID EventID, sentAt time.Time, body json.RawMessage) (*bytes.Buffer, error) {
+	return envelopeFromBody(eventID, sentAt, transactionType, body, nil)
+}
+
+// envelopeFromBody builds an envelope with a single item of the given type
+// holding body, followed by one item for each attachment.
+func envelopeFromBody(
+	eventID EventID,
+	sentAt time.Time,
+	itemType string,
+	body json.RawMessage,
+	attachments []*Attachment,
+) (*bytes.Buffer, error) {
 	var b bytes.Buffer
 	enc := json.NewEncoder(&b)
 	// envelope header
@@ -115,7 +147,7 @@ func transactionEnvelopeFromBody(eventID EventID, sentAt time.Time, body json.Ra
 		Type   string `json:"type"`
 		Length int    `json:"length"`
 	}{
-		Type:   transactionType,
+		Type:   itemType,
 		Length: len(body),
 	})
 	if err != nil {
@@ -126,6 +158,26 @@ func transactionEnvelopeFromBody(eventID EventID, sentAt time.Time, body json.Ra
 	if err != nil {
 		return nil, err
 	}
+	for _, a := range attachments {
+		// attachment item header
+		err = enc.Encode(struct {
+			Type        string `json:"type"`
+			Length      int    `json:"length"`
+			Filename    string `json:"filename"`
+			ContentType string `json:"content_type,omitempty"`
+		}{
+			Type:        "attachment",
+			Length:      len(a.Payload),
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+		})
+		if err != nil {
+			return nil, err
+		}
+		// attachment payload, sent as is
+		b.Write(a.Payload)
+		b.WriteByte('\n')
+	}
 	return &b, nil
 }
 
@@ -145,6 +197,18 @@ func getRequestFromEvent(event *Event, dsn *Dsn) (*http.Request, error) {
 			b,
 		)
 	}
+	// Attachments can only be sent in the same envelope as the event.
+	if len(event.Attachments) > 0 {
+		b, err := envelopeFromBody(event.EventID, time.Now(), "event", body, event.Attachments)
+		if err != nil {
+			return nil, err
+		}
+		return http.NewRequest(
+			http.MethodPost,
+			dsn.EnvelopeAPIURL().String(),
+			b,
+		)
+	}
 	return http.NewRequest(
 		http.MethodPost,
 		dsn.StoreAPIURL().String(),
@@ -165,9 +229,8 @@ type batch struct {
 
 // HTTPTransport is a default implementation of Transport interface used by Client.
 type HTTPTransport struct {
-	dsn       *Dsn
-	client    *http.Client
-	transport http.RoundTripper
+	dsn    *Dsn
+	client *http.Client
 
 	// buffer is a channel of batches. Calling Flush terminates work on the
 	// current in-flight items and starts a new batch for subsequent events.
@@ -212,23 +275,7 @@ func (t *HTTPTransport) Configure(options ClientOptions) {
 		done:    make(chan struct{}),
 	}
 
-	if options.HTTPTransport != nil {
-		t.transport = options.HTTPTransport
-	} else {
-		t.transport = &http.Transport{
-			Proxy:           getProxyConfig(options),
-			TLSClientConfig: getTLSConfig(options),
-		}
-	}
-
-	if options.HTTPClient != nil {
-		t.client = options.HTTPClient
-	} else {
-		t.client = &http.Client{
-			Transport: t.transport,
-			Timeout:   t.Timeout,
-		}
-	}
+	t.client = NewHTTPClient(options, t.Timeout)
 
 	t.start.Do(func() {
 		go t.worker()
@@ -244,11 +291,13 @@ func (t *HTTPTransport) SendEvent(event *Event) {
 	disabled := time.Now().Before(t.disabledUntil)
 	t.mu.RUnlock()
 	if disabled {
+		metrics.dropped(dropReasonRateLimit)
 		return
 	}
 
 	request, err := getRequestFromEvent(event, t.dsn)
 	if err != nil {
+		metrics.dropped(dropReasonMarshal)
 		return
 	}
 
@@ -271,6 +320,7 @@ func (t *HTTPTransport) SendEvent(event *Event) {
 
 	select {
 	case b.items <- request:
+		metrics.queueDepth.Add(1)
 		var eventType string
 		if event.Type == transactionType {
 			eventType = "transaction"
@@ -286,6 +336,7 @@ func (t *HTTPTransport) SendEvent(event *Event) {
 		)
 	default:
 		Logger.Println("Event dropped due to transport buffer being full.")
+		metrics.dropped(dropReasonQueueFull)
 	}
 
 	t.buffer <- b
@@ -361,24 +412,31 @@ func (t *HTTPTransport) worker() {
 
 		// Process all batch items.
 		for request := range b.items {
+			metrics.queueDepth.Add(-1)
+
 			t.mu.RLock()
 			disabled := time.Now().Before(t.disabledUntil)
 			t.mu.RUnlock()
 			if disabled {
+				metrics.dropped(dropReasonRateLimit)
 				continue
 			}
 
+			start := time.Now()
 			response, err := t.client.Do(request)
+			metrics.requestDone(request.ContentLength, time.Since(start), response)
 
 			if err != nil {
 				Logger.Printf("There was an issue with sending an event: %v", err)
 			}
 
 			if response != nil && response.StatusCode == http.StatusTooManyRequests {
-				deadline := time.Now().Add(retryAfter(time.Now(), response))
+				backoff := retryAfter(time.Now(), response)
+				deadline := time.Now().Add(backoff)
 				t.mu.Lock()
 				t.disabledUntil = deadline
 				t.mu.Unlock()
+				metrics.rateLimitBackoff.Add(int64(backoff))
 				Logger.Printf("Too many requests, backing off till: %s\n", deadline)
 			}
 		}
@@ -396,7 +454,6 @@ func (t *HTTPTransport) worker() {
 type HTTPSyncTransport struct {
 	dsn           *Dsn
 	client        *http.Client
-	transport     http.RoundTripper
 	disabledUntil time.Time
 
 	// HTTP Client request timeout. Defaults to 30 seconds.
@@ -421,33 +478,22 @@ func (t *HTTPSyncTransport) Configure(options ClientOptions) {
 	}
 	t.dsn = dsn
 
-	if options.HTTPTransport != nil {
-		t.transport = options.HTTPTransport
-	} else {
-		t.transport = &http.Transport{
-			Proxy:           getProxyConfig(options),
-			TLSClientConfig: getTLSConfig(options),
-		}
-	}
-
-	if options.HTTPClient != nil {
-		t.client = options.HTTPClient
-	} else {
-		t.client = &http.Client{
-			Transport: t.transport,
-			Timeout:   t.Timeout,
-		}
-	}
+	t.client = NewHTTPClient(options, t.Timeout)
 }
 
 // SendEvent assembles a new packet out of Event and sends it to remote server.
 func (t *HTTPSyncTransport) SendEvent(event *Event) {
-	if t.dsn == nil || time.Now().Before(t.disabledUntil) {
+	if t.dsn == nil {
+		return
+	}
+	if time.Now().Before(t.disabledUntil) {
+		metrics.dropped(dropReasonRateLimit)
 		return
 	}
 
 	request, err := getRequestFromEvent(event, t.dsn)
 	if err != nil {
+		metrics.dropped(dropReasonMarshal)
 		return
 	}
 
@@ -469,14 +515,18 @@ func (t *HTTPSyncTransport) SendEvent(event *Event) {
 		t.dsn.projectID,
 	)
 
+	start := time.Now()
 	response, err := t.client.Do(request)
+	metrics.requestDone(request.ContentLength, time.Since(start), response)
 
 	if err != nil {
 		Logger.Printf("There was an issue with sending an event: %v", err)
 	}
 
 	if response != nil && response.StatusCode == http.StatusTooManyRequests {
-		t.disabledUntil = time.Now().Add(retryAfter(time.Now(), response))
+		backoff := retryAfter(time.Now(), response)
+		t.disabledUntil = time.Now().Add(backoff)
+		metrics.rateLimitBackoff.Add(int64(backoff))
 		Logger.Printf("Too many requests, backing off till: %s\n", t.disabledUntil)
 	}
 }
@@ -500,6 +550,7 @@ func (t *noopTransport) Configure(options ClientOptions) {
 
 func (t *noopTransport) SendEvent(event *Event) {
 	Logger.Println("Event dropped due to noopTransport usage.")
+	metrics.dropped(dropReasonNoTransport)
 }
 
 func (t *noopTransport) Flush(_ time.Duration) bool {
@@ -1,6 +1,7 @@
 package sentry
 
 import (
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -147,6 +148,33 @@ func TestTransactionEnvelopeFromBody(t *testing.T) {
 	}
 }
 
+func TestEnvelopeFromBodyWithAttachments(t *testing.T) {
+	const eventID = "b81c5be4d31e48959103a1f878a1efcb"
+	sentAt := time.Unix(0, 0).UTC()
+	body := json.RawMessage(`{"message":"hi"}`)
+	attachments := []*Attachment{
+		{Filename: "a.txt", ContentType: "text/plain", Payload: []byte("hello\nworld")},
+		{Filename: "b.bin", Payload: []byte{}},
+	}
+	b, err := envelopeFromBody(eventID, sentAt, "event", body, attachments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	want := `{"event_id":"b81c5be4d31e48959103a1f878a1efcb","sent_at":"1970-01-01T00:00:00Z"}
+{"type":"event","length":16}
+{"message":"hi"}
+{"type":"attachment","length":11,"filename":"a.txt","content_type":"text/plain"}
+hello
+world
+{"type":"attachment","length":0,"filename":"b.bin"}
+
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Envelope mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestGetRequestFromEvent(t *testing.T) {
 	testCases := []struct {
 		testName string
@@ -170,6 +198,16 @@ func TestGetRequestFromEvent(t *testing.T) {
 			}(),
 			apiURL: "https://host/path/api/42/envelope/",
 		},
+		{
+			testName: "Event with attachments",
+			event: func() *Event {
+				event := NewEvent()
+				event.Attachments = []*Attachment{{Filename: "a.txt"}}
+
+				return event
+			}(),
+			apiURL: "https://host/path/api/42/envelope/",
+		},
 	}
 
 	for _, test := range testCases {
@@ -196,6 +234,26 @@ func TestGetRequestFromEvent(t *testing.T) {
 	}
 }
 
+func TestNewHTTPClient(t *testing.T) {
+	pool := x509.NewCertPool()
+	client := NewHTTPClient(ClientOptions{HTTPProxy: "http://proxy:3128", CaCerts: pool}, time.Second)
+	assertEqual(t, client.Timeout, time.Second)
+	transport := client.Transport.(*http.Transport)
+	if transport.TLSClientConfig == nil || transport.TLSClientConfig.RootCAs != pool {
+		t.Error("CaCerts not used")
+	}
+	proxy, err := transport.Proxy(httptest.NewRequest(http.MethodGet, "https://sentry.io", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, proxy.String(), "http://proxy:3128")
+
+	custom := &http.Client{}
+	if got := NewHTTPClient(ClientOptions{HTTPClient: custom}, time.Second); got != custom {
+		t.Error("HTTPClient option not used")
+	}
+}
+
 func TestRetryAfterNoHeader(t *testing.T) {
 	r := http.Response{}
 	assertEqual(t, retryAfter(time.Now(), &r), time.Second*60)
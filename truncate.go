@@ -0,0 +1,368 @@
+package sentry
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Default payload limits, used when the respective ClientOptions are zero.
+const (
+	defaultMaxEventBytes       = 1000 * 1000
+	defaultMaxBreadcrumbsBytes = 250 * 1000
+	defaultMaxStringLength     = 8192
+	defaultMaxFrames           = 100
+)
+
+// minTrimmedFrames is the number of frames kept in each stack trace when
+// trimming an oversized event.
+const minTrimmedFrames = 20
+
+// truncationExtraKey is the key in Event.Extra that records what parts of an
+// event were trimmed to fit the payload limits.
+const truncationExtraKey = "sentry:truncated"
+
+// limit returns the effective value of a size limit option: the default if
+// the option is zero, no limit (0) if the option is negative.
+func limit(option, defaultValue int) int {
+	switch {
+	case option == 0:
+		return defaultValue
+	case option < 0:
+		return 0
+	default:
+		return option
+	}
+}
+
+// trimEvent enforces the payload limits configured in the client options. It
+// first applies the limits on individual fields, then, if the event is still
+// too big, trims it gradually: dropping source context lines, frames in the
+// middle of stack traces, the oldest breadcrumbs, the request body and the
+// extra data, in this order. What was trimmed is recorded in the event.
+func (client *Client) trimEvent(event *Event) {
+	options := client.Options()
+	var trimmed []string
+
+	if max := limit(options.MaxStringLength, defaultMaxStringLength); max > 0 {
+		if n := truncateEventStrings(event, max); n > 0 {
+			trimmed = append(trimmed, fmt.Sprintf("truncated %d strings to %d bytes", n, max))
+		}
+	}
+	if max := limit(options.MaxFrames, defaultMaxFrames); max > 0 {
+		if n := trimEventFrames(event, max); n > 0 {
+			trimmed = append(trimmed, fmt.Sprintf("omitted %d frames", n))
+		}
+	}
+	if max := limit(options.MaxBreadcrumbsBytes, defaultMaxBreadcrumbsBytes); max > 0 {
+		if n := trimBreadcrumbsBytes(event, max); n > 0 {
+			trimmed = append(trimmed, fmt.Sprintf("dropped %d oldest breadcrumbs", n))
+		}
+	}
+
+	if max := limit(options.MaxEventBytes, defaultMaxEventBytes); max > 0 {
+		trimmed = trimEventSize(event, max, trimmed)
+	}
+
+	if len(trimmed) > 0 {
+		Logger.Printf("Event %s trimmed to fit payload limits: %v", event.EventID, trimmed)
+		if event.Extra == nil {
+			event.Extra = make(map[string]interface{})
+		}
+		event.Extra[truncationExtraKey] = trimmed
+	}
+}
+
+// trimEventSize trims the event until its JSON encoding, including the
+// description of what was trimmed that is added to the extra data afterwards,
+// fits in max bytes or there is nothing left to trim. It returns trimmed with
+// a description of each step taken appended.
+func trimEventSize(event *Event, max int, trimmed []string) []string {
+	fits := func(trimmed []string) bool {
+		b, err := json.Marshal(event)
+		if err != nil {
+			// Events that cannot be encoded are left for the transport to
+			// deal with.
+			return true
+		}
+		size := len(b)
+		if len(trimmed) > 0 {
+			note, _ := json.Marshal(map[string][]string{truncationExtraKey: trimmed})
+			size += len(note)
+		}
+		return size <= max
+	}
+	if fits(trimmed) {
+		return trimmed
+	}
+
+	if removeEventContextLines(event) {
+		trimmed = append(trimmed, "removed source context lines")
+		if fits(trimmed) {
+			return trimmed
+		}
+	}
+
+	if n := trimEventFrames(event, minTrimmedFrames); n > 0 {
+		trimmed = append(trimmed, fmt.Sprintf("omitted %d frames", n))
+		if fits(trimmed) {
+			return trimmed
+		}
+	}
+
+	if len(event.Breadcrumbs) > 0 {
+		total := len(event.Breadcrumbs)
+		dropped := func() string {
+			return fmt.Sprintf("dropped %d oldest breadcrumbs", total-len(event.Breadcrumbs))
+		}
+		for len(event.Breadcrumbs) > 0 {
+			event.Breadcrumbs = event.Breadcrumbs[len(event.Breadcrumbs)/2+len(event.Breadcrumbs)%2:]
+			if fits(append(trimmed, dropped())) {
+				break
+			}
+		}
+		trimmed = append(trimmed, dropped())
+		if fits(trimmed) {
+			return trimmed
+		}
+	}
+
+	if event.Request != nil && event.Request.Data != nil {
+		event.Request.Data = nil
+		trimmed = append(trimmed, "removed request body")
+		if fits(trimmed) {
+			return trimmed
+		}
+	}
+
+	if len(event.Extra) > 0 {
+		event.Extra = nil
+		trimmed = append(trimmed, "removed extra data")
+	}
+
+	return trimmed
+}
+
+// truncateEventStrings shortens the message and the strings in the extra and
+// contexts data of the event to at most max bytes, copying the maps it
+// changes. It returns the number of strings that were truncated.
+func truncateEventStrings(event *Event, max int) int {
+	var n int
+	event.Message, n = truncateString(event.Message, max)
+	if event.LogEntry != nil {
+		entry := *event.LogEntry
+		var m int
+		entry.Formatted, m = truncateString(entry.Formatted, max)
+		if m > 0 {
+			event.LogEntry = &entry
+			n += m
+		}
+	}
+	if extra, ok := truncateValue(event.Extra, max, &n); ok {
+		event.Extra = extra.(map[string]interface{})
+	}
+	if contexts, ok := truncateValue(event.Contexts, max, &n); ok {
+		event.Contexts = contexts.(map[string]interface{})
+	}
+	return n
+}
+
+// truncateString shortens s to at most max bytes, not counting the appended
+// ellipsis, without splitting multibyte characters. It returns the result and
+// 1 if s was truncated, 0 otherwise.
+func truncateString(s string, max int) (string, int) {
+	if len(s) <= max {
+		return s, 0
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "...", 1
+}
+
+// truncateValue truncates strings nested in v. It reports whether v had to be
+// changed, in which case the returned value is a modified copy of v. Values of
+// other types than strings, slices and maps, such as the typed contexts, are
+// copied through their JSON encoding. The number of truncated strings is added
+// to n.
+func truncateValue(v interface{}, max int, n *int) (interface{}, bool) {
+	switch v := v.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64, json.Number:
+		return v, false
+	case string:
+		s, c := truncateString(v, max)
+		*n += c
+		return s, c > 0
+	case []string:
+		var c []string
+		for i, s := range v {
+			if s, t := truncateString(s, max); t > 0 {
+				if c == nil {
+					c = append([]string(nil), v...)
+				}
+				c[i] = s
+				*n += t
+			}
+		}
+		return c, c != nil
+	case map[string]string:
+		var c map[string]string
+		for k, s := range v {
+			if s, t := truncateString(s, max); t > 0 {
+				if c == nil {
+					c = make(map[string]string, len(v))
+					for k, s := range v {
+						c[k] = s
+					}
+				}
+				c[k] = s
+				*n += t
+			}
+		}
+		return c, c != nil
+	case []interface{}:
+		var c []interface{}
+		for i, e := range v {
+			if e, ok := truncateValue(e, max, n); ok {
+				if c == nil {
+					c = append([]interface{}(nil), v...)
+				}
+				c[i] = e
+			}
+		}
+		return c, c != nil
+	case map[string]interface{}:
+		var c map[string]interface{}
+		for k, e := range v {
+			if e, ok := truncateValue(e, max, n); ok {
+				if c == nil {
+					c = make(map[string]interface{}, len(v))
+					for k, e := range v {
+						c[k] = e
+					}
+				}
+				c[k] = e
+			}
+		}
+		return c, c != nil
+	default:
+		decoded, ok := jsonValue(v)
+		if !ok {
+			return v, false
+		}
+		return truncateValue(decoded, max, n)
+	}
+}
+
+// eventStacktraces returns all stack traces of exceptions and threads in the
+// event.
+func eventStacktraces(event *Event) []*Stacktrace {
+	var stacktraces []*Stacktrace
+	for _, ex := range event.Exception {
+		if ex.Stacktrace != nil {
+			stacktraces = append(stacktraces, ex.Stacktrace)
+		}
+	}
+	for _, th := range event.Threads {
+		if th.Stacktrace != nil {
+			stacktraces = append(stacktraces, th.Stacktrace)
+		}
+	}
+	return stacktraces
+}
+
+// trimEventFrames limits all stack traces in the event to at most max frames.
+// It returns the total number of frames omitted.
+func trimEventFrames(event *Event, max int) int {
+	var n int
+	for _, st := range eventStacktraces(event) {
+		n += trimFrames(st, max)
+	}
+	return n
+}
+
+// trimFrames limits the stack trace to at most max frames, keeping the
+// outermost and innermost frames and omitting frames in the middle. The range
+// of omitted frames is recorded in FramesOmitted, merged with the range
+// omitted by a previous trim, if any, so that it refers to the indices of the
+// original frames. It returns the number of frames omitted.
+func trimFrames(st *Stacktrace, max int) int {
+	if len(st.Frames) <= max {
+		return 0
+	}
+	// Frames are sorted from outermost to innermost. The innermost frames,
+	// closer to where the error happened, get the extra frame when max is odd.
+	head := max / 2
+	tail := max - head
+	omitted := len(st.Frames) - max
+
+	frames := make([]Frame, 0, max)
+	frames = append(frames, st.Frames[:head]...)
+	frames = append(frames, st.Frames[len(st.Frames)-tail:]...)
+	st.Frames = frames
+	st.FramesOmitted = mergeFramesOmitted(st.FramesOmitted, uint(head), uint(head+omitted))
+	return omitted
+}
+
+// mergeFramesOmitted merges the range [start, end) of frames omitted from a
+// stack trace, in its current indices, with the range previously omitted.
+func mergeFramesOmitted(previous []uint, start, end uint) []uint {
+	if len(previous) != 2 {
+		return []uint{start, end}
+	}
+	// Frames from previous[0] on were shifted by the previous omission.
+	origin := func(i uint) uint {
+		if i < previous[0] {
+			return i
+		}
+		return i + previous[1] - previous[0]
+	}
+	start, end = origin(start), origin(end-1)+1
+	if previous[0] < start {
+		start = previous[0]
+	}
+	if previous[1] > end {
+		end = previous[1]
+	}
+	return []uint{start, end}
+}
+
+// removeEventContextLines removes the source code context of all frames in
+// the event. It reports whether there was anything to remove.
+func removeEventContextLines(event *Event) bool {
+	var removed bool
+	for _, st := range eventStacktraces(event) {
+		for i := range st.Frames {
+			frame := &st.Frames[i]
+			if frame.PreContext != nil || frame.ContextLine != "" || frame.PostContext != nil {
+				frame.PreContext = nil
+				frame.ContextLine = ""
+				frame.PostContext = nil
+				removed = true
+			}
+		}
+	}
+	return removed
+}
+
+// trimBreadcrumbsBytes drops the oldest breadcrumbs from the event until the
+// total size of their JSON encoding fits in max bytes. It returns the number
+// of breadcrumbs dropped.
+func trimBreadcrumbsBytes(event *Event, max int) int {
+	size := 0
+	for i := len(event.Breadcrumbs) - 1; i >= 0; i-- {
+		b, err := json.Marshal(event.Breadcrumbs[i])
+		if err != nil {
+			// Unserializable breadcrumbs are dealt with by the transport.
+			continue
+		}
+		size += len(b)
+		if size > max {
+			event.Breadcrumbs = event.Breadcrumbs[i+1:]
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,216 @@
+package sentry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"this is too long", 7, "this is..."},
+		// Multibyte characters are never split.
+		{"ééééé", 3, "é..."},
+	}
+	for _, tt := range tests {
+		got, _ := truncateString(tt.in, tt.max)
+		assertEqual(t, got, tt.want, "truncateString(%q, %d)", tt.in, tt.max)
+	}
+}
+
+func TestTruncateEventStringsDoesNotModifySharedValues(t *testing.T) {
+	nested := map[string]interface{}{
+		"long":  strings.Repeat("x", 20),
+		"short": "x",
+	}
+	event := NewEvent()
+	event.Message = strings.Repeat("m", 20)
+	event.Extra["nested"] = nested
+	event.Extra["list"] = []interface{}{strings.Repeat("y", 20), 42}
+	event.Contexts["ctx"] = map[string]string{"k": strings.Repeat("z", 20)}
+	extra := event.Extra
+
+	n := truncateEventStrings(event, 5)
+
+	assertEqual(t, n, 4)
+	assertEqual(t, event.Message, "mmmmm...")
+	assertEqual(t, event.Extra["nested"], map[string]interface{}{"long": "xxxxx...", "short": "x"})
+	assertEqual(t, event.Extra["list"], []interface{}{"yyyyy...", 42})
+	assertEqual(t, event.Contexts["ctx"], map[string]string{"k": "zzzzz..."})
+	assertEqual(t, nested["long"], strings.Repeat("x", 20), "shared map was modified")
+	assertEqual(t, extra["list"], []interface{}{strings.Repeat("y", 20), 42}, "shared map was modified")
+}
+
+func TestTruncateEventStringsTypedContexts(t *testing.T) {
+	event := NewEvent()
+	event.Contexts[OSContextKey] = OSContext{Name: "linux", KernelVersion: strings.Repeat("k", 20)}
+	event.Contexts[RuntimeContextKey] = RuntimeContext{Name: "go"}
+
+	n := truncateEventStrings(event, 5)
+
+	assertEqual(t, n, 1)
+	assertEqual(t, event.Contexts[OSContextKey], map[string]interface{}{
+		"name":           "linux",
+		"kernel_version": "kkkkk...",
+	})
+	assertEqual(t, event.Contexts[RuntimeContextKey], RuntimeContext{Name: "go"})
+}
+
+func framesN(n int) []Frame {
+	frames := make([]Frame, n)
+	for i := range frames {
+		frames[i] = Frame{Function: fmt.Sprintf("f%d", i)}
+	}
+	return frames
+}
+
+func TestTrimFrames(t *testing.T) {
+	st := &Stacktrace{Frames: framesN(10)}
+	n := trimFrames(st, 5)
+
+	assertEqual(t, n, 5)
+	var got []string
+	for _, f := range st.Frames {
+		got = append(got, f.Function)
+	}
+	assertEqual(t, got, []string{"f0", "f1", "f7", "f8", "f9"})
+	assertEqual(t, st.FramesOmitted, []uint{2, 7})
+
+	// Stack traces within the limit are left untouched.
+	st = &Stacktrace{Frames: framesN(3)}
+	assertEqual(t, trimFrames(st, 5), 0)
+	assertEqual(t, len(st.Frames), 3)
+	assertEqual(t, st.FramesOmitted, []uint(nil))
+}
+
+func TestTrimFramesTwice(t *testing.T) {
+	st := &Stacktrace{Frames: framesN(200)}
+	trimFrames(st, 100)
+	assertEqual(t, st.FramesOmitted, []uint{50, 150})
+
+	trimFrames(st, 20)
+	assertEqual(t, st.FramesOmitted, []uint{10, 190})
+	assertEqual(t, st.Frames[9].Function, "f9")
+	assertEqual(t, st.Frames[10].Function, "f190")
+}
+
+func TestTrimEventSizeStopsWhenFits(t *testing.T) {
+	event := NewEvent()
+	event.Breadcrumbs = []*Breadcrumb{{Message: strings.Repeat("b", 2000)}}
+	event.Request = &Request{Data: "small body"}
+
+	trimmed := trimEventSize(event, 1000, nil)
+
+	assertEqual(t, trimmed, []string{"dropped 1 oldest breadcrumbs"})
+	assertEqual(t, event.Request.Data, "small body")
+}
+
+func TestTrimEventSizeIncludesTruncationNote(t *testing.T) {
+	event := NewEvent()
+	event.Request = &Request{Data: strings.Repeat("d", 500)}
+	b, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	max := len(b)
+
+	// The event fits on its own, but not along with the note recording that
+	// its strings were truncated.
+	trimmed := trimEventSize(event, max, []string{"truncated 1 strings to 10 bytes"})
+
+	assertEqual(t, trimmed, []string{"truncated 1 strings to 10 bytes", "removed request body"})
+	event.Extra[truncationExtraKey] = trimmed
+	if b, _ := json.Marshal(event); len(b) > max {
+		t.Errorf("event size = %d, want at most %d", len(b), max)
+	}
+}
+
+func TestTrimBreadcrumbsBytes(t *testing.T) {
+	event := NewEvent()
+	for i := 0; i < 10; i++ {
+		event.Breadcrumbs = append(event.Breadcrumbs, &Breadcrumb{Message: fmt.Sprintf("%03d", i)})
+	}
+	size := func(b *Breadcrumb) int {
+		data, _ := json.Marshal(b)
+		return len(data)
+	}
+	max := 3*size(event.Breadcrumbs[0]) + 1
+
+	n := trimBreadcrumbsBytes(event, max)
+
+	assertEqual(t, n, 7)
+	assertEqual(t, len(event.Breadcrumbs), 3)
+	assertEqual(t, event.Breadcrumbs[0].Message, "007", "oldest breadcrumbs should be dropped first")
+}
+
+func TestClientTrimsOversizedEvent(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{
+		Transport:     transport,
+		MaxEventBytes: 10000,
+		Integrations: func([]Integration) []Integration {
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := NewEvent()
+	event.Message = "oversized"
+	frames := framesN(50)
+	for i := range frames {
+		frames[i].PreContext = []string{strings.Repeat("p", 100)}
+		frames[i].ContextLine = strings.Repeat("c", 100)
+	}
+	event.Exception = []Exception{{Type: "error", Stacktrace: &Stacktrace{Frames: frames}}}
+	for i := 0; i < 100; i++ {
+		event.Breadcrumbs = append(event.Breadcrumbs, &Breadcrumb{Message: strings.Repeat("b", 100)})
+	}
+	client.CaptureEvent(event, nil, nil)
+
+	got := transport.lastEvent
+	if got == nil {
+		t.Fatal("event not sent")
+	}
+	b, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > 10000 {
+		t.Errorf("event size = %d, want at most 10000", len(b))
+	}
+	if len(got.Breadcrumbs) == 0 || len(got.Breadcrumbs) == 100 {
+		t.Errorf("got %d breadcrumbs, want some of the oldest dropped", len(got.Breadcrumbs))
+	}
+	assertEqual(t, got.Exception[0].Stacktrace.Frames[0].ContextLine, "")
+	assertEqual(t, got.Message, "oversized")
+	assertEqual(t, got.Extra[truncationExtraKey], []string{
+		"removed source context lines",
+		"omitted 30 frames",
+		fmt.Sprintf("dropped %d oldest breadcrumbs", 100-len(got.Breadcrumbs)),
+	})
+}
+
+func TestClientTrimsLongStrings(t *testing.T) {
+	transport := &TransportMock{}
+	client, err := NewClient(ClientOptions{
+		Transport:       transport,
+		MaxStringLength: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.CaptureMessage(strings.Repeat("a", 20), nil, nil)
+
+	got := transport.lastEvent
+	assertEqual(t, got.Message, "aaaaaaaaaa...")
+	assertEqual(t, got.Extra[truncationExtraKey], []string{"truncated 1 strings to 10 bytes"})
+}
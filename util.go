@@ -1,6 +1,7 @@
 package sentry
 
 import (
+	"bytes"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
@@ -27,6 +28,22 @@ func fileExists(fileName string) bool {
 	return true
 }
 
+// jsonValue returns v as decoded by encoding/json from its JSON encoding, with
+// numbers decoded as json.Number. It reports whether v could be encoded.
+func jsonValue(v interface{}) (interface{}, bool) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v, false
+	}
+	var decoded interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&decoded); err != nil {
+		return v, false
+	}
+	return decoded, true
+}
+
 //nolint: deadcode, unused
 func prettyPrint(data interface{}) {
 	dbg, _ := json.MarshalIndent(data, "", "  ")